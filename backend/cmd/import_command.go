@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"com.ldap/management/ldap"
+	cli "github.com/urfave/cli/v2"
+)
+
+// connectionFlags select the server and the account a command binds with.
+var connectionFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "host",
+		Usage: "LDAP server host",
+		Value: "localhost",
+	},
+	&cli.IntFlag{
+		Name:  "ldap-port",
+		Usage: "LDAP server port",
+		Value: 389,
+	},
+	&cli.StringFlag{
+		Name:    "user",
+		Usage:   "Login name or bind DN",
+		EnvVars: []string{"LDAP_USER"},
+	},
+	&cli.StringFlag{
+		Name:    "password",
+		Usage:   "Bind password",
+		EnvVars: []string{"LDAP_PASSWORD"},
+	},
+}
+
+// connect binds to the directory described by the command line.
+func connect(c *cli.Context) (*ldap.LDAPOperation, error) {
+	if c.String("user") == "" || c.String("password") == "" {
+		return nil, errors.New("user and password are required")
+	}
+	transport, err := transportOptions(c)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := serverProfile(c)
+	if err != nil {
+		return nil, err
+	}
+	op, _ := ldap.NewLDAPOperation(c.String("user"), c.String("password"), c.String("host"), c.Int("ldap-port"))
+	op.Transport = transport
+	op.Profile = profile
+	if err := op.Connect(); err != nil {
+		op.Close()
+		return nil, fmt.Errorf("connect to LDAP server: %w", err)
+	}
+	return op, nil
+}
+
+var ImportCommand = &cli.Command{
+	Name:      "import",
+	Usage:     "Apply an LDIF file to the directory",
+	ArgsUsage: "<file.ldif>",
+	Flags: append(append([]cli.Flag{
+		&cli.BoolFlag{
+			Name:  "continue-on-error",
+			Usage: "Keep applying records after a record failed",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Validate the records against the schema without writing",
+		},
+	}, connectionFlags...), directoryFlags...),
+	Action: func(c *cli.Context) error {
+		if c.NArg() != 1 {
+			return errors.New("please give the LDIF file to import")
+		}
+		file, err := os.Open(c.Args().First())
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		records, err := ldap.ParseLDIF(file)
+		if err != nil {
+			return err
+		}
+
+		op, err := connect(c)
+		if err != nil {
+			return err
+		}
+		defer op.Close()
+
+		opts := ldap.ImportOptions{
+			ContinueOnError: c.Bool("continue-on-error"),
+			DryRun:          c.Bool("dry-run"),
+		}
+		if opts.DryRun {
+			if err := op.GetObjectClassAttributes(); err != nil {
+				fmt.Fprintln(os.Stderr, "schema not loaded, only basic checks are done:", err)
+			}
+			opts.Schema = op.Schema()
+		}
+
+		failed := 0
+		for _, result := range ldap.ImportLDIF(op, records, opts) {
+			fmt.Printf("line %d\t%s\t%s\t%s", result.Line, result.ChangeType, result.Status, result.DN)
+			if result.Error != "" {
+				failed++
+				fmt.Printf("\t%s", result.Error)
+			}
+			fmt.Println()
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d records failed", failed, len(records))
+		}
+		return nil
+	},
+}
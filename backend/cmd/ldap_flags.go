@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"com.ldap/management/ldap"
+	cli "github.com/urfave/cli/v2"
+)
+
+var defaultProfile = ldap.DefaultServerProfile()
+
+// directoryFlags configure how to reach and bind to the directory, shared by all commands.
+var directoryFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "ldap-transport",
+		Usage: "Default LDAP transport: plain, starttls or ldaps",
+		Value: ldap.TransportPlain,
+	},
+	&cli.StringFlag{
+		Name:  "ldap-ca-file",
+		Usage: "PEM CA bundle used to verify the LDAP server certificate",
+	},
+	&cli.StringFlag{
+		Name:  "ldap-cert-file",
+		Usage: "PEM client certificate presented to the LDAP server",
+	},
+	&cli.StringFlag{
+		Name:  "ldap-key-file",
+		Usage: "PEM private key of the client certificate",
+	},
+	&cli.StringFlag{
+		Name:  "ldap-server-name",
+		Usage: "Server name expected in the LDAP server certificate",
+	},
+	&cli.BoolFlag{
+		Name:  "ldap-insecure-skip-verify",
+		Usage: "Do not verify the LDAP server certificate (testing only)",
+	},
+	&cli.StringFlag{
+		Name:  "base-dn",
+		Usage: "Naming context to manage, discovered from the root DSE when empty",
+	},
+	&cli.StringFlag{
+		Name:  "admin-dn",
+		Usage: "Bind DN used when logging in as admin, {base} is replaced by the base DN",
+		Value: defaultProfile.AdminDN,
+	},
+	&cli.StringFlag{
+		Name:  "user-dn-template",
+		Usage: "Bind DN template of users, {user} is the login name; empty enables search-then-bind",
+		Value: defaultProfile.UserDNTemplate,
+	},
+	&cli.StringFlag{
+		Name:  "user-filter",
+		Usage: "Filter locating a user for search-then-bind, {user} is the login name",
+		Value: defaultProfile.UserFilter,
+	},
+	&cli.StringFlag{
+		Name:  "lookup-dn",
+		Usage: "DN used to search users for search-then-bind, anonymous when empty",
+	},
+	&cli.StringFlag{
+		Name:    "lookup-password",
+		Usage:   "Password of the lookup DN",
+		EnvVars: []string{"LDAP_LOOKUP_PASSWORD"},
+	},
+	&cli.StringFlag{
+		Name:  "password-hash",
+		Usage: "Hash of userPassword when the server lacks Password Modify: SSHA, SSHA512, CRYPT-SHA256 or CRYPT-SHA512",
+		Value: defaultProfile.PasswordHash,
+	},
+	&cli.StringFlag{
+		Name:  "empty-group-member",
+		Usage: "Placeholder member keeping an emptied groupOfNames valid, {base} is replaced by the base DN; empty refuses to remove the last member",
+		Value: defaultProfile.EmptyGroupMember,
+	},
+	&cli.StringFlag{
+		Name:  "uid-number-range",
+		Usage: "Range uidNumber of new posix accounts is allocated from, as min-max",
+		Value: defaultProfile.UIDNumbers.String(),
+	},
+	&cli.StringFlag{
+		Name:  "gid-number-range",
+		Usage: "Range gidNumber of new posix groups is allocated from, as min-max",
+		Value: defaultProfile.GIDNumbers.String(),
+	},
+	&cli.StringFlag{
+		Name:  "id-pool-dn",
+		Usage: "sambaUnixIdPool entry holding the next uidNumber and gidNumber, {base} is replaced by the base DN; empty allocates after the highest number in use",
+	},
+	&cli.StringFlag{
+		Name:  "home-directory",
+		Usage: "homeDirectory of new posix accounts, {user} is replaced by the uid",
+		Value: defaultProfile.HomeDirectory,
+	},
+	&cli.StringFlag{
+		Name:  "login-shell",
+		Usage: "loginShell of new posix accounts",
+		Value: defaultProfile.LoginShell,
+	},
+	&cli.StringFlag{
+		Name:  "group-parent",
+		Usage: "Parent of the primary groups created with posix accounts, {base} is replaced by the base DN",
+		Value: defaultProfile.GroupParent,
+	},
+	&cli.StringFlag{
+		Name:  "account-lock",
+		Usage: "How accounts are locked and disabled: auto, ppolicy, 389ds or ad",
+		Value: "auto",
+	},
+	&cli.StringFlag{
+		Name:  "member-of",
+		Usage: "Whether the server maintains memberOf: auto uses it when the entry has values, on or off",
+		Value: "auto",
+	},
+	&cli.StringFlag{
+		Name:  "password-policy-dn",
+		Usage: "Default ppolicy entry for accounts without pwdPolicySubentry, {base} is replaced by the base DN",
+	},
+	&cli.BoolFlag{
+		Name:  "skip-schema-validation",
+		Usage: "Send adds and modifies without checking them against the schema, the server still enforces it",
+	},
+}
+
+func transportOptions(c *cli.Context) (ldap.TransportOptions, error) {
+	mode, err := ldap.ParseTransportMode(c.String("ldap-transport"))
+	if err != nil {
+		return ldap.TransportOptions{}, err
+	}
+	return ldap.TransportOptions{
+		Mode:               mode,
+		CAFile:             c.String("ldap-ca-file"),
+		CertFile:           c.String("ldap-cert-file"),
+		KeyFile:            c.String("ldap-key-file"),
+		ServerName:         c.String("ldap-server-name"),
+		InsecureSkipVerify: c.Bool("ldap-insecure-skip-verify"),
+	}, nil
+}
+
+func serverProfile(c *cli.Context) (ldap.ServerProfile, error) {
+	hash, err := ldap.ParseHashScheme(c.String("password-hash"))
+	if err != nil {
+		return ldap.ServerProfile{}, err
+	}
+	uids, err := ldap.ParseIDRange(c.String("uid-number-range"))
+	if err != nil {
+		return ldap.ServerProfile{}, err
+	}
+	gids, err := ldap.ParseIDRange(c.String("gid-number-range"))
+	if err != nil {
+		return ldap.ServerProfile{}, err
+	}
+	lock, err := ldap.ParseAccountLock(c.String("account-lock"))
+	if err != nil {
+		return ldap.ServerProfile{}, err
+	}
+	memberOf, err := ldap.ParseMemberOf(c.String("member-of"))
+	if err != nil {
+		return ldap.ServerProfile{}, err
+	}
+	return ldap.ServerProfile{
+		BaseDN:           c.String("base-dn"),
+		AdminDN:          c.String("admin-dn"),
+		UserDNTemplate:   c.String("user-dn-template"),
+		UserFilter:       c.String("user-filter"),
+		LookupDN:         c.String("lookup-dn"),
+		LookupPassword:   c.String("lookup-password"),
+		PasswordHash:     hash,
+		EmptyGroupMember: c.String("empty-group-member"),
+		UIDNumbers:       uids,
+		GIDNumbers:       gids,
+		IDPoolDN:         c.String("id-pool-dn"),
+		HomeDirectory:    c.String("home-directory"),
+		LoginShell:       c.String("login-shell"),
+		GroupParent:      c.String("group-parent"),
+		AccountLock:      lock,
+		MemberOf:         memberOf,
+		PasswordPolicyDN: c.String("password-policy-dn"),
+		SkipValidation:   c.Bool("skip-schema-validation"),
+	}, nil
+}
@@ -1,24 +1,68 @@
 package cmd
 
 import (
+	"errors"
+	"io/fs"
+	"time"
+
+	"com.ldap/management/ldap"
 	"com.ldap/management/web"
+	log "github.com/sirupsen/logrus"
 	cli "github.com/urfave/cli/v2"
 )
 
 var WebCommand = &cli.Command{
 	Name:  "start",
 	Usage: "Start the web server",
-	Flags: []cli.Flag{
+	Flags: append([]cli.Flag{
 		&cli.IntFlag{
 			Name:    "port",
 			Aliases: []string{""},
 			Usage:   "Web server port",
 			Value:   8080,
 		},
-	},
+		&cli.DurationFlag{
+			Name:  "session-idle-timeout",
+			Usage: "Close LDAP sessions idle for longer than this duration",
+			Value: 30 * time.Minute,
+		},
+		&cli.StringFlag{
+			Name:  "template-dir",
+			Usage: "Directory with the entry templates (*.yaml, *.yml, *.json)",
+			Value: "./templates",
+		},
+		&cli.BoolFlag{
+			Name:  "allow-client-transport",
+			Usage: "Let the login form weaken the transport, supply a CA certificate, a server name or skip certificate verification",
+		},
+		&cli.BoolFlag{
+			Name:  "legacy-comma-split",
+			Usage: "Split string values of the old add format at commas, as older UIs expect; array values are never split",
+		},
+	}, directoryFlags...),
 	Action: func(c *cli.Context) error {
 		port := c.Int("port")
 		route := web.NewRouter()
+		route.Sessions.IdleTimeout = c.Duration("session-idle-timeout")
+		transport, err := transportOptions(c)
+		if err != nil {
+			return err
+		}
+		route.Transport = transport
+		route.AllowClientTransport = c.Bool("allow-client-transport")
+		if route.Profile, err = serverProfile(c); err != nil {
+			return err
+		}
+		route.LegacyCommaSplit = c.Bool("legacy-comma-split")
+		templates, err := ldap.LoadTemplates(c.String("template-dir"))
+		switch {
+		case err == nil:
+			route.Templates = templates
+		case errors.Is(err, fs.ErrNotExist):
+			log.Warnf("template directory %s not found, no templates available", c.String("template-dir"))
+		default:
+			return err
+		}
 		route.StartWebServer(port)
 		return nil
 	},
@@ -9,7 +9,7 @@ type LdapObjClassOperation interface {
 
 func (op *LDAPOperation)GetObjAttrs(dn string) ([]string,[]string) {
 
-	obj, exist := op.ObjParser.Objects[dn]
+	obj, exist := op.Schema().Objects[dn]
 
 	if exist {
 		return obj.Must,obj.May
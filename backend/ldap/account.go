@@ -0,0 +1,309 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+// mechanisms locking and disabling accounts
+const (
+	AccountLockPPolicy = "ppolicy" // pwdAccountLockedTime of the OpenLDAP ppolicy overlay
+	AccountLock389     = "389ds"   // nsAccountLock of 389 Directory Server
+	AccountLockAD      = "ad"      // userAccountControl and lockoutTime of Active Directory
+)
+
+// ParseAccountLock normalizes a configured mechanism, empty means detect it from the server.
+func ParseAccountLock(mechanism string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(mechanism)) {
+	case "", "auto":
+		return "", nil
+	case AccountLockPPolicy, "openldap":
+		return AccountLockPPolicy, nil
+	case AccountLock389, "389", "389-ds", "nsaccountlock":
+		return AccountLock389, nil
+	case AccountLockAD, "activedirectory", "active-directory":
+		return AccountLockAD, nil
+	}
+	return "", fmt.Errorf("unknown account lock %q, expect one of auto, ppolicy, 389ds, ad", mechanism)
+}
+
+// actions changing the state of an account
+const (
+	AccountLock    = "lock"
+	AccountUnlock  = "unlock"
+	AccountDisable = "disable"
+	AccountEnable  = "enable"
+)
+
+const (
+	// ppolicyDisabled in pwdAccountLockedTime locks the account until an administrator unlocks it.
+	ppolicyDisabled = "000001010000Z"
+
+	uacAccountDisable   = 0x2
+	uacLockout          = 0x10
+	uacPasswordExpired  = 0x800000
+	uacComputed         = "msDS-User-Account-Control-Computed"
+	generalizedTimeZulu = "20060102150405Z"
+)
+
+var accountStatusAttributes = []string{
+	"objectClass",
+	"pwdAccountLockedTime", "pwdChangedTime", "pwdGraceUseTime", "pwdPolicySubentry",
+	"nsAccountLock", "accountUnlockTime", "passwordExpirationTime",
+	"userAccountControl", "lockoutTime", uacComputed,
+}
+
+// ErrLockNotSupported is returned for a lock the server has no means for.
+var ErrLockNotSupported = errors.New("Active Directory locks accounts only after failed logins, disable the account instead")
+
+// accountClasses mark entries that can log in.
+var accountClasses = []string{"person", "organizationalPerson", "inetOrgPerson", "posixAccount", "shadowAccount", "account", "user"}
+
+// AccountStatus tells whether an account can log in. GraceLoginsLeft is
+// only set when the password policy grants grace logins.
+type AccountStatus struct {
+	Mechanism       string     `json:"mechanism"`
+	Locked          bool       `json:"locked"`
+	Disabled        bool       `json:"disabled"`
+	PasswordExpired bool       `json:"passwordExpired"`
+	GraceLoginsLeft *int       `json:"graceLoginsLeft,omitempty"`
+	LockedSince     *time.Time `json:"lockedSince,omitempty"`
+	UnlockAt        *time.Time `json:"unlockAt,omitempty"` // end of a lockout after failed logins
+}
+
+// IsAccount reports whether entry has an object class of a login account.
+func IsAccount(entry *gldap.Entry) bool {
+	return slices.ContainsFunc(entry.GetAttributeValues("objectClass"), func(class string) bool {
+		return slices.ContainsFunc(accountClasses, func(account string) bool { return strings.EqualFold(class, account) })
+	})
+}
+
+// AccountLockMechanism returns the configured mechanism or detects it: Active
+// Directory by its capability, 389 Directory Server by its vendor or schema,
+// and the ppolicy overlay otherwise.
+func (op *LDAPOperation) AccountLockMechanism() (string, error) {
+	if op.Profile.AccountLock != "" {
+		return op.Profile.AccountLock, nil
+	}
+	dse, err := op.RootDSE()
+	if err != nil {
+		return "", err
+	}
+	if slices.Contains(dse.SupportedCapabilities, ActiveDirectoryCapability) {
+		return AccountLockAD, nil
+	}
+	vendor := strings.ToLower(dse.VendorName)
+	if strings.Contains(vendor, "389") || strings.Contains(vendor, "fedora") || strings.Contains(vendor, "red hat") {
+		return AccountLock389, nil
+	}
+	if _, exist := op.Schema().AttributeType("nsAccountLock"); exist {
+		return AccountLock389, nil
+	}
+	return AccountLockPPolicy, nil
+}
+
+// readAccount reads the attributes deciding the status of dn, most of them are operational.
+func (op *LDAPOperation) readAccount(dn string) (*gldap.Entry, error) {
+	page, err := op.SearchPaged(SearchOptions{
+		BaseDN:     dn,
+		Scope:      "base",
+		Filter:     "(objectClass=*)",
+		Attributes: accountStatusAttributes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(page.Entries) == 0 {
+		return nil, gldap.NewError(gldap.LDAPResultNoSuchObject, errors.New(dn+" not found"))
+	}
+	return page.Entries[0], nil
+}
+
+// AccountStatus reads whether dn is locked, disabled or has an expired password.
+func (op *LDAPOperation) AccountStatus(dn string) (*AccountStatus, error) {
+	mechanism, err := op.AccountLockMechanism()
+	if err != nil {
+		return nil, err
+	}
+	entry, err := op.readAccount(dn)
+	if err != nil {
+		return nil, err
+	}
+	var policy *PasswordPolicy
+	if mechanism == AccountLockPPolicy {
+		// without a readable policy lockouts count as permanent and passwords never expire
+		if policy, err = op.policyOf(entry); err != nil {
+			policy = nil
+		}
+	}
+	return accountStatus(mechanism, entry, policy, time.Now()), nil
+}
+
+func accountStatus(mechanism string, entry *gldap.Entry, policy *PasswordPolicy, now time.Time) *AccountStatus {
+	status := &AccountStatus{Mechanism: mechanism}
+	timeOf := func(name string) (time.Time, bool) {
+		value := entry.GetEqualFoldAttributeValue(name)
+		if value == "" {
+			return time.Time{}, false
+		}
+		t, err := ParseGeneralizedTime(value)
+		return t, err == nil
+	}
+
+	switch mechanism {
+	case AccountLockPPolicy:
+		if locked := entry.GetEqualFoldAttributeValue("pwdAccountLockedTime"); locked == ppolicyDisabled {
+			status.Locked, status.Disabled = true, true
+		} else if since, ok := timeOf("pwdAccountLockedTime"); ok {
+			status.Locked, status.LockedSince = true, &since
+			if policy != nil && policy.LockoutDuration > 0 {
+				unlock := since.Add(policy.LockoutDuration)
+				status.Locked = now.Before(unlock)
+				status.UnlockAt = &unlock
+			}
+		}
+		if policy != nil && policy.MaxAge > 0 {
+			if changed, ok := timeOf("pwdChangedTime"); ok {
+				status.PasswordExpired = !now.Before(changed.Add(policy.MaxAge))
+			}
+		}
+		if policy != nil && policy.GraceAuthNLimit > 0 {
+			left := max(policy.GraceAuthNLimit-len(entry.GetEqualFoldAttributeValues("pwdGraceUseTime")), 0)
+			status.GraceLoginsLeft = &left
+		}
+
+	case AccountLock389:
+		status.Disabled = strings.EqualFold(entry.GetEqualFoldAttributeValue("nsAccountLock"), "true")
+		status.Locked = status.Disabled
+		// a lockout after failed logins lasts until accountUnlockTime, 1970 means until reset
+		if unlock, ok := timeOf("accountUnlockTime"); ok && (unlock.Unix() == 0 || now.Before(unlock)) {
+			status.Locked = true
+			if unlock.Unix() != 0 {
+				status.UnlockAt = &unlock
+			}
+		}
+		if expires, ok := timeOf("passwordExpirationTime"); ok {
+			status.PasswordExpired = !now.Before(expires)
+		}
+
+	case AccountLockAD:
+		uac, _ := strconv.ParseInt(entry.GetEqualFoldAttributeValue("userAccountControl"), 10, 64)
+		status.Disabled = uac&uacAccountDisable != 0
+		if computed := entry.GetEqualFoldAttributeValue(uacComputed); computed != "" {
+			flags, _ := strconv.ParseInt(computed, 10, 64)
+			status.Locked = flags&uacLockout != 0
+			status.PasswordExpired = flags&uacPasswordExpired != 0
+		} else {
+			lockout := entry.GetEqualFoldAttributeValue("lockoutTime")
+			status.Locked = lockout != "" && lockout != "0"
+		}
+	}
+	return status
+}
+
+// ChangeAccount locks, unlocks, disables or enables dn, action is one of the
+// Account* actions. On ppolicy a lock is a lockout as after failed logins,
+// lasting pwdLockoutDuration, and disable locks until an administrator
+// unlocks. 389 Directory Server has one administrative lock, nsAccountLock,
+// used for both. Active Directory can not be locked on request, only disabled.
+func (op *LDAPOperation) ChangeAccount(dn, action string) (*AccountStatus, error) {
+	if op.Conn == nil {
+		return nil, errors.New("LDAP connection is not established")
+	}
+	mechanism, err := op.AccountLockMechanism()
+	if err != nil {
+		return nil, err
+	}
+	entry, err := op.readAccount(dn)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := accountChanges(mechanism, action, entry, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) > 0 {
+		// the lock attributes are operational, they bypass the schema validation of ModifyRecord
+		modifyReq := gldap.NewModifyRequest(dn, nil)
+		for _, change := range changes {
+			switch change.Operation {
+			case ModifyReplace:
+				modifyReq.Replace(change.Attribute, change.Values)
+			case ModifyDelete:
+				modifyReq.Delete(change.Attribute, change.Values)
+			}
+		}
+		if err := op.Conn.Modify(modifyReq); err != nil {
+			return nil, err
+		}
+	}
+	return op.AccountStatus(dn)
+}
+
+// accountChanges returns the modification performing action, nothing when
+// the account is already in that state.
+func accountChanges(mechanism, action string, entry *gldap.Entry, now time.Time) ([]AttributeChange, error) {
+	has := func(name string) bool { return entry.GetEqualFoldAttributeValue(name) != "" }
+	remove := func(names ...string) []AttributeChange {
+		var changes []AttributeChange
+		for _, name := range names {
+			if has(name) {
+				changes = append(changes, AttributeChange{Operation: ModifyDelete, Attribute: name})
+			}
+		}
+		return changes
+	}
+
+	switch mechanism {
+	case AccountLockPPolicy:
+		switch action {
+		case AccountLock:
+			// a disabled account stays disabled
+			if entry.GetEqualFoldAttributeValue("pwdAccountLockedTime") == ppolicyDisabled {
+				return nil, nil
+			}
+			return []AttributeChange{{Operation: ModifyReplace, Attribute: "pwdAccountLockedTime", Values: []string{now.UTC().Format(generalizedTimeZulu)}}}, nil
+		case AccountDisable:
+			return []AttributeChange{{Operation: ModifyReplace, Attribute: "pwdAccountLockedTime", Values: []string{ppolicyDisabled}}}, nil
+		case AccountUnlock, AccountEnable:
+			return remove("pwdAccountLockedTime", "pwdFailureTime"), nil
+		}
+
+	case AccountLock389:
+		switch action {
+		case AccountLock, AccountDisable:
+			return []AttributeChange{{Operation: ModifyReplace, Attribute: "nsAccountLock", Values: []string{"TRUE"}}}, nil
+		case AccountUnlock:
+			return remove("nsAccountLock", "accountUnlockTime", "passwordRetryCount"), nil
+		case AccountEnable:
+			return remove("nsAccountLock"), nil
+		}
+
+	case AccountLockAD:
+		switch action {
+		case AccountLock:
+			return nil, ErrLockNotSupported
+		case AccountUnlock:
+			return []AttributeChange{{Operation: ModifyReplace, Attribute: "lockoutTime", Values: []string{"0"}}}, nil
+		case AccountDisable, AccountEnable:
+			value := entry.GetEqualFoldAttributeValue("userAccountControl")
+			uac, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s has no valid userAccountControl: %q", entry.DN, value)
+			}
+			if action == AccountDisable {
+				uac |= uacAccountDisable
+			} else {
+				uac &^= uacAccountDisable
+			}
+			return []AttributeChange{{Operation: ModifyReplace, Attribute: "userAccountControl", Values: []string{strconv.FormatInt(uac, 10)}}}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown account action %q for %s, expect one of lock, unlock, disable, enable", action, mechanism)
+}
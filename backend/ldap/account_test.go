@@ -0,0 +1,110 @@
+package ldap
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+func TestAccountStatus(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	policy := &PasswordPolicy{MaxAge: 90 * 24 * time.Hour, GraceAuthNLimit: 3, LockoutDuration: time.Hour}
+	values := []struct {
+		name      string
+		mechanism string
+		attrs     map[string][]string
+		policy    *PasswordPolicy
+		expect    AccountStatus
+		grace     int // -1 when no grace logins are reported
+	}{
+		{"ppolicy active", AccountLockPPolicy, map[string][]string{"pwdChangedTime": {"20240501000000Z"}}, policy,
+			AccountStatus{}, 3},
+		{"ppolicy lockout", AccountLockPPolicy, map[string][]string{"pwdAccountLockedTime": {"20240601113000Z"}}, policy,
+			AccountStatus{Locked: true}, 3},
+		{"ppolicy lockout over", AccountLockPPolicy, map[string][]string{"pwdAccountLockedTime": {"20240601100000Z"}}, policy,
+			AccountStatus{}, 3},
+		{"ppolicy lockout without policy", AccountLockPPolicy, map[string][]string{"pwdAccountLockedTime": {"20240601100000Z"}}, nil,
+			AccountStatus{Locked: true}, -1},
+		{"ppolicy disabled", AccountLockPPolicy, map[string][]string{"pwdAccountLockedTime": {"000001010000Z"}}, policy,
+			AccountStatus{Locked: true, Disabled: true}, 3},
+		{"ppolicy expired with grace logins used", AccountLockPPolicy,
+			map[string][]string{"pwdChangedTime": {"20240101000000Z"}, "pwdGraceUseTime": {"20240530000000Z", "20240531000000Z"}}, policy,
+			AccountStatus{PasswordExpired: true}, 1},
+		{"389 disabled", AccountLock389, map[string][]string{"nsAccountLock": {"true"}}, nil,
+			AccountStatus{Locked: true, Disabled: true}, -1},
+		{"389 lockout", AccountLock389, map[string][]string{"accountUnlockTime": {"20240601130000Z"}, "passwordExpirationTime": {"20240501000000Z"}}, nil,
+			AccountStatus{Locked: true, PasswordExpired: true}, -1},
+		{"389 lockout until reset", AccountLock389, map[string][]string{"accountUnlockTime": {"19700101000000Z"}}, nil,
+			AccountStatus{Locked: true}, -1},
+		{"ad disabled", AccountLockAD, map[string][]string{"userAccountControl": {"514"}, "lockoutTime": {"0"}}, nil,
+			AccountStatus{Disabled: true}, -1},
+		{"ad locked and expired", AccountLockAD, map[string][]string{"userAccountControl": {"512"}, uacComputed: {"8388624"}}, nil,
+			AccountStatus{Locked: true, PasswordExpired: true}, -1},
+		{"ad locked by lockoutTime", AccountLockAD, map[string][]string{"userAccountControl": {"512"}, "lockoutTime": {"133620000000000000"}}, nil,
+			AccountStatus{Locked: true}, -1},
+	}
+	for _, value := range values {
+		status := accountStatus(value.mechanism, gldap.NewEntry("uid=alice,dc=example,dc=com", value.attrs), value.policy, now)
+		if status.Mechanism != value.mechanism || status.Locked != value.expect.Locked || status.Disabled != value.expect.Disabled ||
+			status.PasswordExpired != value.expect.PasswordExpired {
+			t.Errorf("%s: get %+v, expect %+v", value.name, status, value.expect)
+		}
+		switch {
+		case value.grace < 0 && status.GraceLoginsLeft != nil:
+			t.Errorf("%s: expect no grace logins, get %d", value.name, *status.GraceLoginsLeft)
+		case value.grace >= 0 && (status.GraceLoginsLeft == nil || *status.GraceLoginsLeft != value.grace):
+			t.Errorf("%s: expect %d grace logins, get %v", value.name, value.grace, status.GraceLoginsLeft)
+		}
+	}
+}
+
+func TestAccountChanges(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	values := []struct {
+		mechanism string
+		action    string
+		attrs     map[string][]string
+		expect    []string
+	}{
+		{AccountLockPPolicy, AccountLock, nil, []string{"replace pwdAccountLockedTime 20240601120000Z"}},
+		{AccountLockPPolicy, AccountLock, map[string][]string{"pwdAccountLockedTime": {"000001010000Z"}}, nil},
+		{AccountLockPPolicy, AccountDisable, nil, []string{"replace pwdAccountLockedTime 000001010000Z"}},
+		{AccountLockPPolicy, AccountUnlock, map[string][]string{"pwdAccountLockedTime": {"20240601110000Z"}, "pwdFailureTime": {"20240601110000Z"}},
+			[]string{"delete pwdAccountLockedTime ", "delete pwdFailureTime "}},
+		{AccountLockPPolicy, AccountEnable, nil, nil},
+		{AccountLock389, AccountDisable, nil, []string{"replace nsAccountLock TRUE"}},
+		{AccountLock389, AccountUnlock, map[string][]string{"nsAccountLock": {"TRUE"}, "passwordRetryCount": {"3"}},
+			[]string{"delete nsAccountLock ", "delete passwordRetryCount "}},
+		{AccountLockAD, AccountDisable, map[string][]string{"userAccountControl": {"512"}}, []string{"replace userAccountControl 514"}},
+		{AccountLockAD, AccountEnable, map[string][]string{"userAccountControl": {"514"}}, []string{"replace userAccountControl 512"}},
+		{AccountLockAD, AccountUnlock, nil, []string{"replace lockoutTime 0"}},
+	}
+	for _, value := range values {
+		changes, err := accountChanges(value.mechanism, value.action, gldap.NewEntry("uid=alice,dc=example,dc=com", value.attrs), now)
+		if err != nil {
+			t.Errorf("%s %s: %v", value.mechanism, value.action, err)
+			continue
+		}
+		var got []string
+		for _, change := range changes {
+			values := ""
+			if len(change.Values) > 0 {
+				values = change.Values[0]
+			}
+			got = append(got, change.Operation+" "+change.Attribute+" "+values)
+		}
+		if !slices.Equal(got, value.expect) {
+			t.Errorf("%s %s: get %v, expect %v", value.mechanism, value.action, got, value.expect)
+		}
+	}
+
+	if _, err := accountChanges(AccountLockAD, AccountLock, gldap.NewEntry("cn=alice", nil), now); !errors.Is(err, ErrLockNotSupported) {
+		t.Errorf("expect ErrLockNotSupported, get %v", err)
+	}
+	if _, err := accountChanges(AccountLockPPolicy, "freeze", gldap.NewEntry("cn=alice", nil), now); err == nil {
+		t.Error("expect error for an unknown action")
+	}
+}
@@ -0,0 +1,229 @@
+package ldap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// usages of an attribute type
+const (
+	UserApplications     = "userApplications"
+	DirectoryOperation   = "directoryOperation"
+	DistributedOperation = "distributedOperation"
+	DSAOperation         = "dSAOperation"
+)
+
+type AttributeType struct {
+	Oid                string              `json:"oid"`
+	Name               []string            `json:"name"`
+	Description        string              `json:"description"`
+	Obsolete           bool                `json:"obsolete"`
+	Superior           string              `json:"superior"`
+	Equality           string              `json:"equality"`
+	Ordering           string              `json:"ordering"`
+	Substring          string              `json:"substring"`
+	Syntax             string              `json:"syntax"`
+	SyntaxLength       int                 `json:"syntaxLength,omitempty"`
+	SingleValue        bool                `json:"singleValue"`
+	Collective         bool                `json:"collective"`
+	NoUserModification bool                `json:"noUserModification"`
+	Usage              string              `json:"usage"`
+	Extensions         map[string][]string `json:"extensions,omitempty"`
+}
+
+type MatchingRule struct {
+	Oid         string              `json:"oid"`
+	Name        []string            `json:"name"`
+	Description string              `json:"description"`
+	Obsolete    bool                `json:"obsolete"`
+	Syntax      string              `json:"syntax"`
+	Extensions  map[string][]string `json:"extensions,omitempty"`
+}
+
+type LDAPSyntax struct {
+	Oid         string              `json:"oid"`
+	Description string              `json:"description"`
+	Extensions  map[string][]string `json:"extensions,omitempty"`
+}
+
+var attributeTypeKeywords = map[string]int{
+	"NAME":                 argQDescrs,
+	"DESC":                 argQDString,
+	"OBSOLETE":             argNone,
+	"SUP":                  argOID,
+	"EQUALITY":             argOID,
+	"ORDERING":             argOID,
+	"SUBSTR":               argOID,
+	"SYNTAX":               argOID,
+	"SINGLE-VALUE":         argNone,
+	"COLLECTIVE":           argNone,
+	"NO-USER-MODIFICATION": argNone,
+	"USAGE":                argWord,
+}
+
+var matchingRuleKeywords = map[string]int{
+	"NAME":     argQDescrs,
+	"DESC":     argQDString,
+	"OBSOLETE": argNone,
+	"SYNTAX":   argOID,
+}
+
+var ldapSyntaxKeywords = map[string]int{
+	"DESC": argQDString,
+}
+
+// ParseAttributeType reads an AttributeTypeDescription (RFC 4512 4.1.2), e.g.
+// ( 2.5.4.3 NAME ( 'cn' 'commonName' ) DESC 'RFC4519: common name(s) for which the entity is known by' SUP name )
+func (p *ObjectClassParser) ParseAttributeType(presention string) (*AttributeType, error) {
+	desc, err := parseSchemaDescription(presention, attributeTypeKeywords)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attributeType %q: %w", presention, err)
+	}
+	attr := &AttributeType{
+		Oid:                desc.OID,
+		Name:               desc.Values["NAME"],
+		Description:        desc.first("DESC"),
+		Obsolete:           desc.has("OBSOLETE"),
+		Superior:           desc.first("SUP"),
+		Equality:           desc.first("EQUALITY"),
+		Ordering:           desc.first("ORDERING"),
+		Substring:          desc.first("SUBSTR"),
+		SingleValue:        desc.has("SINGLE-VALUE"),
+		Collective:         desc.has("COLLECTIVE"),
+		NoUserModification: desc.has("NO-USER-MODIFICATION"),
+		Usage:              UserApplications,
+	}
+	if len(desc.Extensions) > 0 {
+		attr.Extensions = desc.Extensions
+	}
+	if syntax := desc.first("SYNTAX"); syntax != "" {
+		if attr.Syntax, attr.SyntaxLength, err = parseNoidLen(syntax); err != nil {
+			return nil, fmt.Errorf("invalid attributeType %s: %w", attr.Oid, err)
+		}
+	}
+	if usage := desc.first("USAGE"); usage != "" {
+		switch usage {
+		case UserApplications, DirectoryOperation, DistributedOperation, DSAOperation:
+			attr.Usage = usage
+		default:
+			return nil, fmt.Errorf("invalid attributeType %s: unknown usage %s", attr.Oid, usage)
+		}
+	}
+	if attr.Superior == "" && attr.Syntax == "" {
+		return nil, fmt.Errorf("invalid attributeType %s: neither SUP nor SYNTAX given", attr.Oid)
+	}
+	// RFC 4512 allows NO-USER-MODIFICATION only on operational attributes, but Active Directory
+	// publishes objectGUID, whenCreated or uSNChanged with userApplications, so it is accepted
+	if len(attr.Name) == 0 {
+		attr.Name = []string{attr.Oid}
+	}
+
+	for _, nm := range attr.Name {
+		p.AttributeTypes[nm] = attr
+	}
+	p.attributeIndex[strings.ToLower(attr.Oid)] = attr
+	for _, nm := range attr.Name {
+		p.attributeIndex[strings.ToLower(nm)] = attr
+	}
+	return attr, nil
+}
+
+// ParseMatchingRule reads a MatchingRuleDescription (RFC 4512 4.1.3).
+func (p *ObjectClassParser) ParseMatchingRule(presention string) (*MatchingRule, error) {
+	desc, err := parseSchemaDescription(presention, matchingRuleKeywords)
+	if err != nil {
+		return nil, fmt.Errorf("invalid matchingRule %q: %w", presention, err)
+	}
+	rule := &MatchingRule{
+		Oid:         desc.OID,
+		Name:        desc.Values["NAME"],
+		Description: desc.first("DESC"),
+		Obsolete:    desc.has("OBSOLETE"),
+		Syntax:      desc.first("SYNTAX"),
+	}
+	if len(desc.Extensions) > 0 {
+		rule.Extensions = desc.Extensions
+	}
+	if rule.Syntax == "" {
+		return nil, fmt.Errorf("invalid matchingRule %s: SYNTAX is required", rule.Oid)
+	}
+	if len(rule.Name) == 0 {
+		rule.Name = []string{rule.Oid}
+	}
+	for _, nm := range rule.Name {
+		p.MatchingRules[nm] = rule
+	}
+	return rule, nil
+}
+
+// ParseLDAPSyntax reads a SyntaxDescription (RFC 4512 4.1.5).
+func (p *ObjectClassParser) ParseLDAPSyntax(presention string) (*LDAPSyntax, error) {
+	desc, err := parseSchemaDescription(presention, ldapSyntaxKeywords)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ldapSyntax %q: %w", presention, err)
+	}
+	syntax := &LDAPSyntax{
+		Oid:         desc.OID,
+		Description: desc.first("DESC"),
+	}
+	if len(desc.Extensions) > 0 {
+		syntax.Extensions = desc.Extensions
+	}
+	p.Syntaxes[syntax.Oid] = syntax
+	return syntax, nil
+}
+
+// parseNoidLen splits 1.3.6.1.4.1.1466.115.121.1.15{256} into oid and length.
+func parseNoidLen(value string) (string, int, error) {
+	open := strings.IndexByte(value, '{')
+	if open < 0 {
+		return value, 0, nil
+	}
+	if !strings.HasSuffix(value, "}") {
+		return "", 0, fmt.Errorf("invalid syntax length in %s", value)
+	}
+	length, err := strconv.Atoi(value[open+1 : len(value)-1])
+	if err != nil || length < 0 {
+		return "", 0, fmt.Errorf("invalid syntax length in %s", value)
+	}
+	return value[:open], length, nil
+}
+
+// AttributeType looks an attribute type up by any of its names or its oid, ignoring case.
+func (p *ObjectClassParser) AttributeType(name string) (*AttributeType, bool) {
+	attr, exist := p.attributeIndex[strings.ToLower(name)]
+	return attr, exist
+}
+
+// EffectiveAttributeType returns the attribute type with SYNTAX and matching
+// rules inherited from its superiors filled in.
+func (p *ObjectClassParser) EffectiveAttributeType(name string) (*AttributeType, bool) {
+	attr, exist := p.AttributeType(name)
+	if !exist {
+		return nil, false
+	}
+	effective := *attr
+	seen := map[*AttributeType]bool{attr: true}
+	for sup := attr.Superior; sup != ""; {
+		parent, exist := p.AttributeType(sup)
+		if !exist || seen[parent] {
+			break
+		}
+		seen[parent] = true
+		if effective.Syntax == "" {
+			effective.Syntax, effective.SyntaxLength = parent.Syntax, parent.SyntaxLength
+		}
+		if effective.Equality == "" {
+			effective.Equality = parent.Equality
+		}
+		if effective.Ordering == "" {
+			effective.Ordering = parent.Ordering
+		}
+		if effective.Substring == "" {
+			effective.Substring = parent.Substring
+		}
+		sup = parent.Superior
+	}
+	return &effective, true
+}
@@ -0,0 +1,126 @@
+package ldap
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseAttributeType(t *testing.T) {
+	values := []struct {
+		input  string
+		expect AttributeType
+	}{
+		{
+			input: "( 2.5.4.41 NAME 'name' DESC 'RFC4519: common supertype of name attributes' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15{32768} )",
+			expect: AttributeType{Oid: "2.5.4.41", Name: []string{"name"}, Description: "RFC4519: common supertype of name attributes",
+				Equality: "caseIgnoreMatch", Substring: "caseIgnoreSubstringsMatch", Syntax: "1.3.6.1.4.1.1466.115.121.1.15",
+				SyntaxLength: 32768, Usage: UserApplications},
+		},
+		{
+			input: "( 2.5.4.3 NAME ( 'cn' 'commonName' ) DESC 'RFC4519: common name(s) for which the entity is known by' SUP name )",
+			expect: AttributeType{Oid: "2.5.4.3", Name: []string{"cn", "commonName"},
+				Description: "RFC4519: common name(s) for which the entity is known by", Superior: "name", Usage: UserApplications},
+		},
+		{
+			input: "( 1.3.6.1.1.1.1.0 NAME 'uidNumber' DESC 'RFC2307: An integer uniquely identifying a user in an administrative domain' EQUALITY integerMatch ORDERING integerOrderingMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 SINGLE-VALUE )",
+			expect: AttributeType{Oid: "1.3.6.1.1.1.1.0", Name: []string{"uidNumber"},
+				Description: "RFC2307: An integer uniquely identifying a user in an administrative domain",
+				Equality:    "integerMatch", Ordering: "integerOrderingMatch", Syntax: "1.3.6.1.4.1.1466.115.121.1.27",
+				SingleValue: true, Usage: UserApplications},
+		},
+		{
+			input: "( 2.5.18.1 NAME 'createTimestamp' DESC 'RFC4512: time which object was created' EQUALITY generalizedTimeMatch ORDERING generalizedTimeOrderingMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.24 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )",
+			expect: AttributeType{Oid: "2.5.18.1", Name: []string{"createTimestamp"}, Description: "RFC4512: time which object was created",
+				Equality: "generalizedTimeMatch", Ordering: "generalizedTimeOrderingMatch", Syntax: "1.3.6.1.4.1.1466.115.121.1.24",
+				SingleValue: true, NoUserModification: true, Usage: DirectoryOperation},
+		},
+		{
+			input: "( 2.5.4.20 NAME 'telephoneNumber' EQUALITY telephoneNumberMatch SUBSTR telephoneNumberSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.50 COLLECTIVE X-ORIGIN 'RFC 4519' )",
+			expect: AttributeType{Oid: "2.5.4.20", Name: []string{"telephoneNumber"}, Equality: "telephoneNumberMatch",
+				Substring: "telephoneNumberSubstringsMatch", Syntax: "1.3.6.1.4.1.1466.115.121.1.50", Collective: true,
+				Usage: UserApplications, Extensions: map[string][]string{"X-ORIGIN": {"RFC 4519"}}},
+		},
+		{
+			// Active Directory quotes the syntax oid
+			input: "( 1.2.840.113556.1.4.8 NAME 'userAccountControl' SYNTAX '1.3.6.1.4.1.1466.115.121.1.27' SINGLE-VALUE )",
+			expect: AttributeType{Oid: "1.2.840.113556.1.4.8", Name: []string{"userAccountControl"},
+				Syntax: "1.3.6.1.4.1.1466.115.121.1.27", SingleValue: true, Usage: UserApplications},
+		},
+		{
+			// Active Directory marks user attributes as NO-USER-MODIFICATION
+			input: "( 1.2.840.113556.1.4.2 NAME 'objectGUID' SYNTAX '1.3.6.1.4.1.1466.115.121.1.40' SINGLE-VALUE NO-USER-MODIFICATION )",
+			expect: AttributeType{Oid: "1.2.840.113556.1.4.2", Name: []string{"objectGUID"},
+				Syntax: "1.3.6.1.4.1.1466.115.121.1.40", SingleValue: true, NoUserModification: true, Usage: UserApplications},
+		},
+	}
+
+	parser := NewObjectClassParser()
+	for _, value := range values {
+		attr, err := parser.ParseAttributeType(value.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := value.expect
+		if attr.Oid != expect.Oid || attr.Description != expect.Description || attr.Superior != expect.Superior ||
+			attr.Equality != expect.Equality || attr.Ordering != expect.Ordering || attr.Substring != expect.Substring ||
+			attr.Syntax != expect.Syntax || attr.SyntaxLength != expect.SyntaxLength || attr.SingleValue != expect.SingleValue ||
+			attr.Collective != expect.Collective || attr.NoUserModification != expect.NoUserModification || attr.Usage != expect.Usage {
+			t.Errorf("get value: %+v, expect: %+v", *attr, expect)
+		}
+		if !slices.Equal(attr.Name, expect.Name) {
+			t.Errorf("get value: %v, expect: %v", attr.Name, expect.Name)
+		}
+		for key, values := range expect.Extensions {
+			if !slices.Equal(attr.Extensions[key], values) {
+				t.Errorf("get %s: %v, expect: %v", key, attr.Extensions[key], values)
+			}
+		}
+	}
+
+	// lookups ignore case and accept the oid
+	for _, name := range []string{"CN", "commonname", "2.5.4.3"} {
+		if attr, exist := parser.AttributeType(name); !exist || attr.Oid != "2.5.4.3" {
+			t.Errorf("lookup of %s failed", name)
+		}
+	}
+	cn, _ := parser.EffectiveAttributeType("cn")
+	if cn.Syntax != "1.3.6.1.4.1.1466.115.121.1.15" || cn.Equality != "caseIgnoreMatch" || cn.SyntaxLength != 32768 {
+		t.Errorf("cn does not inherit from name: %+v", *cn)
+	}
+
+	invalids := []string{
+		"( 1.2.3 NAME 'nothing' )",
+		"( 1.2.3 NAME 'bad' SYNTAX 1.2{x} )",
+		"( 1.2.3 NAME 'bad' SYNTAX 1.2 USAGE everybody )",
+	}
+	for _, input := range invalids {
+		if _, err := parser.ParseAttributeType(input); err == nil {
+			t.Errorf("expect error for %s", input)
+		}
+	}
+}
+
+func TestParseMatchingRuleAndSyntax(t *testing.T) {
+	parser := NewObjectClassParser()
+	rule, err := parser.ParseMatchingRule("( 2.5.13.2 NAME 'caseIgnoreMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Oid != "2.5.13.2" || rule.Syntax != "1.3.6.1.4.1.1466.115.121.1.15" || parser.MatchingRules["caseIgnoreMatch"] != rule {
+		t.Errorf("unexpected matching rule: %+v", *rule)
+	}
+	if _, err := parser.ParseMatchingRule("( 2.5.13.2 NAME 'caseIgnoreMatch' )"); err == nil {
+		t.Error("expect error for matching rule without syntax")
+	}
+
+	syntax, err := parser.ParseLDAPSyntax("( 1.3.6.1.4.1.1466.115.121.1.5 DESC 'Binary' X-NOT-HUMAN-READABLE 'TRUE' )")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if syntax.Description != "Binary" || !slices.Equal(syntax.Extensions["X-NOT-HUMAN-READABLE"], []string{"TRUE"}) {
+		t.Errorf("unexpected syntax: %+v", *syntax)
+	}
+	if parser.Syntaxes["1.3.6.1.4.1.1466.115.121.1.5"] != syntax {
+		t.Error("syntax is not registered")
+	}
+}
@@ -0,0 +1,68 @@
+package ldap
+
+import (
+	"strings"
+
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+// ParentDN returns the dn without its first RDN, the naming context's parent is "".
+func ParentDN(dn string) (string, error) {
+	parsed, err := gldap.ParseDN(dn)
+	if err != nil {
+		return "", err
+	}
+	if len(parsed.RDNs) <= 1 {
+		return "", nil
+	}
+	parent := &gldap.DN{RDNs: parsed.RDNs[1:]}
+	return parent.String(), nil
+}
+
+// FirstRDN returns the leading RDN of dn, e.g. uid=alice for uid=alice,ou=person.
+func FirstRDN(dn string) (string, error) {
+	parsed, err := gldap.ParseDN(dn)
+	if err != nil {
+		return "", err
+	}
+	if len(parsed.RDNs) == 0 {
+		return "", nil
+	}
+	return parsed.RDNs[0].String(), nil
+}
+
+// JoinDN appends the parent to the rdn.
+func JoinDN(rdn, parent string) string {
+	if parent == "" {
+		return rdn
+	}
+	return rdn + "," + parent
+}
+
+// RDNValue returns the value of the first RDN attribute, e.g. alice for uid=alice,ou=person.
+func RDNValue(dn string) string {
+	parsed, err := gldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return ""
+	}
+	return parsed.RDNs[0].Attributes[0].Value
+}
+
+// SameDN compares two DNs the way the server would, ignoring case and spacing.
+func SameDN(a, b string) bool {
+	pa, errA := gldap.ParseDN(a)
+	pb, errB := gldap.ParseDN(b)
+	if errA != nil || errB != nil {
+		return strings.EqualFold(a, b)
+	}
+	return pa.EqualFold(pb)
+}
+
+// DNKey normalizes dn for use as a map key, DNs equal by SameDN get the same key.
+func DNKey(dn string) string {
+	parsed, err := gldap.ParseDN(dn)
+	if err != nil {
+		return strings.ToLower(dn)
+	}
+	return strings.ToLower(parsed.String())
+}
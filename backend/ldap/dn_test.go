@@ -0,0 +1,33 @@
+package ldap
+
+import "testing"
+
+func TestDNHelpers(t *testing.T) {
+	dn := `cn=Smith\, John,ou=person,dc=example,dc=com`
+	parent, err := ParentDN(dn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent != "ou=person,dc=example,dc=com" {
+		t.Errorf("get value: %s, expect: ou=person,dc=example,dc=com", parent)
+	}
+	rdn, err := FirstRDN(dn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rdn != `cn=Smith\, John` {
+		t.Errorf(`get value: %s, expect: cn=Smith\, John`, rdn)
+	}
+	if value := RDNValue(dn); value != "Smith, John" {
+		t.Errorf("get value: %s, expect: Smith, John", value)
+	}
+	if JoinDN(rdn, parent) != dn {
+		t.Errorf("get value: %s, expect: %s", JoinDN(rdn, parent), dn)
+	}
+	if !SameDN("OU=Person, DC=Example,DC=com", "ou=person,dc=example,dc=com") {
+		t.Error("expect dns to be equal")
+	}
+	if parent, _ := ParentDN("dc=com"); parent != "" {
+		t.Errorf("get value: %s, expect empty parent", parent)
+	}
+}
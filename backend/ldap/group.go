@@ -0,0 +1,372 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+// member attributes of the supported group classes
+const (
+	MemberAttribute       = "member"       // groupOfNames
+	UniqueMemberAttribute = "uniqueMember" // groupOfUniqueNames
+	MemberUIDAttribute    = "memberUid"    // posixGroup
+)
+
+// groupClasses maps a group objectClass to its member attribute, in the
+// order the attributes are preferred for listing members.
+var groupClasses = []struct {
+	objectClass string
+	attribute   string
+	mustMember  bool // the class needs at least one member
+}{
+	{"groupOfNames", MemberAttribute, true},
+	{"groupOfUniqueNames", UniqueMemberAttribute, true},
+	{"posixGroup", MemberUIDAttribute, false},
+	{"group", MemberAttribute, false}, // Active Directory
+}
+
+const groupFilter = "(|(objectClass=groupOfNames)(objectClass=groupOfUniqueNames)(objectClass=posixGroup)(objectClass=group))"
+
+// ErrLastMember is returned when the last member of a group that needs one
+// is removed and no placeholder member is configured.
+var ErrLastMember = errors.New("the group needs at least one member")
+
+type Group struct {
+	DN               string   `json:"dn"`
+	Name             string   `json:"name"`
+	Description      string   `json:"description"`
+	ObjectClasses    []string `json:"objectClasses"`
+	MemberAttributes []string `json:"memberAttributes"` // member, uniqueMember and/or memberUid
+	Members          []string `json:"members"`          // values of the first member attribute
+	MemberCount      int      `json:"memberCount"`
+	values           map[string][]string
+	placeholders     map[string][]string
+	mustMember       map[string]bool
+}
+
+type GroupMember struct {
+	DN          string `json:"dn"` // empty when a memberUid matches no account
+	UID         string `json:"uid,omitempty"`
+	DisplayName string `json:"displayName"`
+	Resolved    bool   `json:"resolved"`
+}
+
+// Groups manages groups and their members through an LdapOperation.
+type Groups struct {
+	Op          LdapOperation
+	Base        string // where groups and accounts are searched
+	EmptyMember string // placeholder DN keeping a groupOfNames valid, "" refuses to empty it
+	InChain     bool   // the server evaluates LDAP_MATCHING_RULE_IN_CHAIN (Active Directory)
+	MemberOf    string // MemberOfOn, MemberOfOff or empty to detect it from the entries
+}
+
+// NewGroups manages the groups below the naming context of op.
+func NewGroups(op *LDAPOperation) (*Groups, error) {
+	base, err := op.BaseDN()
+	if err != nil {
+		return nil, err
+	}
+	groups := &Groups{
+		Op:          op,
+		Base:        base,
+		EmptyMember: op.Profile.expand(op.Profile.EmptyGroupMember, base, ""),
+		MemberOf:    op.Profile.MemberOf,
+	}
+	if dse, err := op.RootDSE(); err == nil {
+		groups.InChain = slices.Contains(dse.SupportedCapabilities, ActiveDirectoryCapability)
+	}
+	return groups, nil
+}
+
+// newGroup builds a group from its entry, ok is false when the entry is no group.
+func (g *Groups) newGroup(entry *gldap.Entry) (*Group, bool) {
+	group := &Group{
+		DN:            entry.DN,
+		Name:          entry.GetAttributeValue("cn"),
+		Description:   entry.GetAttributeValue("description"),
+		ObjectClasses: entry.GetAttributeValues("objectClass"),
+		values:        make(map[string][]string),
+		placeholders:  make(map[string][]string),
+		mustMember:    make(map[string]bool),
+	}
+	for _, class := range groupClasses {
+		if !slices.ContainsFunc(group.ObjectClasses, func(oc string) bool { return strings.EqualFold(oc, class.objectClass) }) {
+			continue
+		}
+		var values []string
+		for _, value := range entry.GetEqualFoldAttributeValues(class.attribute) {
+			if class.attribute != MemberUIDAttribute && g.isPlaceholder(value) {
+				group.placeholders[class.attribute] = append(group.placeholders[class.attribute], value)
+			} else {
+				values = append(values, value)
+			}
+		}
+		if slices.Contains(group.MemberAttributes, class.attribute) {
+			continue
+		}
+		group.MemberAttributes = append(group.MemberAttributes, class.attribute)
+		group.values[class.attribute] = values
+		group.mustMember[class.attribute] = class.mustMember
+	}
+	if len(group.MemberAttributes) == 0 {
+		return nil, false
+	}
+	group.Members = group.values[group.MemberAttributes[0]]
+	if group.Members == nil {
+		group.Members = []string{}
+	}
+	group.MemberCount = len(group.Members)
+	if group.Name == "" {
+		group.Name = RDNValue(entry.DN)
+	}
+	return group, true
+}
+
+func (g *Groups) isPlaceholder(value string) bool {
+	return g.EmptyMember != "" && SameDN(memberDN(value), g.EmptyMember)
+}
+
+var optionalUIDPattern = regexp.MustCompile(`#'[01]*'B$`)
+
+// memberDN strips the optional unique identifier of a uniqueMember value, e.g. uid=a,dc=example,dc=com#'0101'B.
+func memberDN(value string) string {
+	return optionalUIDPattern.ReplaceAllString(value, "")
+}
+
+// List returns the groups below base, the naming context when base is empty.
+func (g *Groups) List(base string) ([]*Group, error) {
+	if base == "" {
+		base = g.Base
+	}
+	entries, err := g.Op.Search(base, groupFilter)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]*Group, 0, len(entries))
+	for _, entry := range entries {
+		if group, ok := g.newGroup(entry); ok {
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
+
+// Get reads one group.
+func (g *Groups) Get(dn string) (*Group, error) {
+	entries, err := g.Op.GetAttrOfObjectClass(dn)
+	if err != nil {
+		return nil, err
+	}
+	group, ok := g.newGroup(entries[0])
+	if !ok {
+		return nil, fmt.Errorf("%s is not a groupOfNames, groupOfUniqueNames, posixGroup or group", dn)
+	}
+	return group, nil
+}
+
+// Members resolves the members of a group to their entries and display names.
+func (g *Groups) Members(dn string) ([]GroupMember, error) {
+	group, err := g.Get(dn)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]GroupMember, 0, len(group.Members))
+	if group.MemberAttributes[0] != MemberUIDAttribute {
+		for _, value := range group.Members {
+			member := GroupMember{DN: memberDN(value), DisplayName: RDNValue(memberDN(value))}
+			if entries, err := g.Op.GetAttrOfObjectClass(member.DN); err == nil {
+				member.Resolved = true
+				member.UID = entries[0].GetAttributeValue("uid")
+				member.DisplayName = displayName(entries[0])
+			}
+			members = append(members, member)
+		}
+		return members, nil
+	}
+
+	accounts, err := g.accountsByUID(group.Members)
+	if err != nil {
+		return nil, err
+	}
+	for _, uid := range group.Members {
+		member := GroupMember{UID: uid, DisplayName: uid}
+		if entry, exist := accounts[uid]; exist {
+			member.DN = entry.DN
+			member.DisplayName = displayName(entry)
+			member.Resolved = true
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// displayName picks the most readable name of an entry.
+func displayName(entry *gldap.Entry) string {
+	for _, attr := range []string{"displayName", "cn", "uid"} {
+		if value := entry.GetAttributeValue(attr); value != "" {
+			return value
+		}
+	}
+	return RDNValue(entry.DN)
+}
+
+// accountsByUID finds the entries of the given uids below Base.
+func (g *Groups) accountsByUID(uids []string) (map[string]*gldap.Entry, error) {
+	accounts := make(map[string]*gldap.Entry, len(uids))
+	if len(uids) == 0 {
+		return accounts, nil
+	}
+	var filter strings.Builder
+	filter.WriteString("(|")
+	for _, uid := range uids {
+		filter.WriteString("(uid=" + gldap.EscapeFilter(uid) + ")")
+	}
+	filter.WriteString(")")
+	entries, err := g.Op.Search(g.Base, filter.String())
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		for _, uid := range entry.GetAttributeValues("uid") {
+			if slices.Contains(uids, uid) {
+				accounts[uid] = entry
+			}
+		}
+	}
+	return accounts, nil
+}
+
+// resolveMember turns a member given as DN or uid into both forms.
+func (g *Groups) resolveMember(member string, needDN, needUID bool) (dn, uid string, err error) {
+	if parsed, err := gldap.ParseDN(member); err == nil && len(parsed.RDNs) > 0 {
+		dn, uid = member, RDNValue(member)
+		if needUID {
+			// the uid attribute wins over the rdn, e.g. for cn=John Doe,ou=person
+			if entries, err := g.Op.GetAttrOfObjectClass(dn); err == nil {
+				if value := entries[0].GetAttributeValue("uid"); value != "" {
+					uid = value
+				}
+			}
+		}
+		return dn, uid, nil
+	}
+	uid = member
+	if needDN {
+		accounts, err := g.accountsByUID([]string{uid})
+		if err != nil {
+			return "", "", err
+		}
+		entry, exist := accounts[uid]
+		if !exist {
+			return "", "", fmt.Errorf("no account with uid %s found", uid)
+		}
+		dn = entry.DN
+	}
+	return dn, uid, nil
+}
+
+// containsValue compares DN valued attributes as DNs and memberUid exactly.
+func containsValue(attribute string, values []string, value string) (string, bool) {
+	for _, item := range values {
+		if attribute == MemberUIDAttribute && item == value ||
+			attribute != MemberUIDAttribute && SameDN(memberDN(item), value) {
+			return item, true
+		}
+	}
+	return "", false
+}
+
+// AddMembers adds members given as DN or uid to every member attribute of
+// the group, members already present are skipped.
+func (g *Groups) AddMembers(groupDN string, members []string) error {
+	group, err := g.Get(groupDN)
+	if err != nil {
+		return err
+	}
+	needUID := slices.Contains(group.MemberAttributes, MemberUIDAttribute)
+	needDN := len(group.MemberAttributes) > 1 || !needUID
+	additions := make(map[string][]string)
+	for _, member := range members {
+		dn, uid, err := g.resolveMember(member, needDN, needUID)
+		if err != nil {
+			return err
+		}
+		for _, attr := range group.MemberAttributes {
+			value := dn
+			if attr == MemberUIDAttribute {
+				value = uid
+			}
+			if _, exist := containsValue(attr, append(group.values[attr], additions[attr]...), value); !exist {
+				additions[attr] = append(additions[attr], value)
+			}
+		}
+	}
+
+	var changes []AttributeChange
+	for _, attr := range group.MemberAttributes {
+		if len(additions[attr]) == 0 {
+			continue
+		}
+		changes = append(changes, AttributeChange{Operation: ModifyAdd, Attribute: attr, Values: additions[attr]})
+		// the placeholder is not needed anymore
+		if placeholders := group.placeholders[attr]; len(placeholders) > 0 {
+			changes = append(changes, AttributeChange{Operation: ModifyDelete, Attribute: attr, Values: placeholders})
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return g.Op.ModifyRecord(groupDN, changes)
+}
+
+// RemoveMembers removes members given as DN or uid from every member
+// attribute of the group. A groupOfNames losing its last member keeps the
+// placeholder member, without a placeholder ErrLastMember is returned.
+func (g *Groups) RemoveMembers(groupDN string, members []string) error {
+	group, err := g.Get(groupDN)
+	if err != nil {
+		return err
+	}
+	needUID := slices.Contains(group.MemberAttributes, MemberUIDAttribute)
+	needDN := len(group.MemberAttributes) > 1 || !needUID
+	removals := make(map[string][]string)
+	for _, member := range members {
+		dn, uid, err := g.resolveMember(member, needDN, needUID)
+		if err != nil {
+			return err
+		}
+		for _, attr := range group.MemberAttributes {
+			value := dn
+			if attr == MemberUIDAttribute {
+				value = uid
+			}
+			if stored, exist := containsValue(attr, group.values[attr], value); exist && !slices.Contains(removals[attr], stored) {
+				removals[attr] = append(removals[attr], stored)
+			}
+		}
+	}
+
+	var changes []AttributeChange
+	for _, attr := range group.MemberAttributes {
+		if len(removals[attr]) == 0 {
+			continue
+		}
+		// a placeholder already stored keeps the group valid
+		if group.mustMember[attr] && len(removals[attr]) == len(group.values[attr]) && len(group.placeholders[attr]) == 0 {
+			if g.EmptyMember == "" {
+				return fmt.Errorf("remove all members of %s: %w", groupDN, ErrLastMember)
+			}
+			changes = append(changes, AttributeChange{Operation: ModifyAdd, Attribute: attr, Values: []string{g.EmptyMember}})
+		}
+		changes = append(changes, AttributeChange{Operation: ModifyDelete, Attribute: attr, Values: removals[attr]})
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return g.Op.ModifyRecord(groupDN, changes)
+}
@@ -0,0 +1,310 @@
+package ldap
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+// memoryDirectory keeps entries in memory. Search ignores the filter and
+// returns every entry below the base, callers filter what they need.
+type memoryDirectory struct {
+	LDAPOperation
+	entries  map[string]map[string][]string
+	changes  []string
+	onModify func(dn string) // runs before a modify, e.g. to simulate a concurrent writer
+}
+
+func (m *memoryDirectory) entry(dn string) *gldap.Entry {
+	for key, attrs := range m.entries {
+		if SameDN(key, dn) {
+			return gldap.NewEntry(key, attrs)
+		}
+	}
+	return nil
+}
+
+func (m *memoryDirectory) Search(baseDN, filter string) ([]*gldap.Entry, error) {
+	var result []*gldap.Entry
+	for dn := range m.entries {
+		if strings.HasSuffix(strings.ToLower(dn), strings.ToLower(baseDN)) {
+			result = append(result, m.entry(dn))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].DN < result[j].DN })
+	return result, nil
+}
+
+// SearchPaged answers base searches, enough to read operational attributes such as memberOf.
+func (m *memoryDirectory) SearchPaged(opts SearchOptions) (*SearchPage, error) {
+	if entry := m.entry(opts.BaseDN); entry != nil && opts.Scope == "base" {
+		return &SearchPage{Entries: []*gldap.Entry{entry}}, nil
+	}
+	return nil, gldap.NewError(gldap.LDAPResultNoSuchObject, errors.New("no such object"))
+}
+
+func (m *memoryDirectory) GetAttrOfObjectClass(dn string) ([]*gldap.Entry, error) {
+	if entry := m.entry(dn); entry != nil {
+		return []*gldap.Entry{entry}, nil
+	}
+	return nil, gldap.NewError(gldap.LDAPResultNoSuchObject, errors.New("no such object"))
+}
+
+func (m *memoryDirectory) ModifyRecord(dn string, changes []AttributeChange) error {
+	entry := m.entry(dn)
+	if entry == nil {
+		return gldap.NewError(gldap.LDAPResultNoSuchObject, errors.New("no such object"))
+	}
+	if m.onModify != nil {
+		m.onModify(dn)
+		entry = m.entry(dn)
+	}
+	for _, change := range changes {
+		if change.Operation == ModifyDelete {
+			for _, value := range change.Values {
+				if !slices.Contains(entry.GetEqualFoldAttributeValues(change.Attribute), value) {
+					return gldap.NewError(gldap.LDAPResultNoSuchAttribute, errors.New("no such value "+value))
+				}
+			}
+		}
+	}
+	for _, change := range changes {
+		m.changes = append(m.changes, change.Operation+" "+change.Attribute+" "+strings.Join(change.Values, "|"))
+	}
+	m.entries[entry.DN] = ApplyChanges(EntryAttributes(entry), changes)
+	return nil
+}
+
+func (m *memoryDirectory) AddEntry(dn string, attrs map[string][]string) error {
+	if m.entry(dn) != nil {
+		return gldap.NewError(gldap.LDAPResultEntryAlreadyExists, errors.New("already exists"))
+	}
+	m.changes = append(m.changes, "add entry "+dn)
+	m.entries[dn] = attrs
+	return nil
+}
+
+func (m *memoryDirectory) DeleteRecord(dn string) error {
+	entry := m.entry(dn)
+	if entry == nil {
+		return gldap.NewError(gldap.LDAPResultNoSuchObject, errors.New("no such object"))
+	}
+	m.changes = append(m.changes, "delete entry "+dn)
+	delete(m.entries, entry.DN)
+	return nil
+}
+
+func groupDirectory() *memoryDirectory {
+	return &memoryDirectory{entries: map[string]map[string][]string{
+		"uid=alice,ou=person,dc=example,dc=com": {"objectClass": {"inetOrgPerson", "posixAccount"}, "uid": {"alice"}, "cn": {"Alice"}, "displayName": {"Alice Liddell"}},
+		"uid=bob,ou=person,dc=example,dc=com":   {"objectClass": {"inetOrgPerson"}, "uid": {"bob"}, "cn": {"Bob"}},
+		"cn=admins,ou=group,dc=example,dc=com":  {"objectClass": {"groupOfNames"}, "cn": {"admins"}, "member": {"uid=alice,ou=person,dc=example,dc=com"}},
+		"cn=staff,ou=group,dc=example,dc=com":   {"objectClass": {"groupOfUniqueNames"}, "cn": {"staff"}, "uniqueMember": {"uid=bob,ou=person,dc=example,dc=com#'0101'B", "uid=gone,ou=person,dc=example,dc=com"}},
+		"cn=users,ou=group,dc=example,dc=com":   {"objectClass": {"posixGroup"}, "cn": {"users"}, "gidNumber": {"100"}, "memberUid": {"alice", "carol"}},
+		"cn=dev,ou=group,dc=example,dc=com":     {"objectClass": {"groupOfNames", "posixGroup"}, "cn": {"dev"}, "gidNumber": {"200"}, "member": {"cn=nobody,dc=example,dc=com"}},
+	}}
+}
+
+func TestListGroups(t *testing.T) {
+	groups := &Groups{Op: groupDirectory(), Base: "dc=example,dc=com", EmptyMember: "cn=nobody,dc=example,dc=com"}
+	list, err := groups.List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, group := range list {
+		names = append(names, group.Name+":"+strings.Join(group.MemberAttributes, ","))
+	}
+	expect := []string{"admins:member", "dev:member,memberUid", "staff:uniqueMember", "users:memberUid"}
+	if !slices.Equal(names, expect) {
+		t.Errorf("get groups %v, expect %v", names, expect)
+	}
+	if list[1].MemberCount != 0 {
+		t.Errorf("the placeholder is no member, get %v", list[1].Members)
+	}
+
+	members, err := groups.Members("cn=staff,ou=group,dc=example,dc=com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 || members[0].DisplayName != "Bob" || !members[0].Resolved || members[1].Resolved || members[1].DisplayName != "gone" {
+		t.Errorf("unexpected members %+v", members)
+	}
+	members, err = groups.Members("cn=users,ou=group,dc=example,dc=com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 || members[0].DN != "uid=alice,ou=person,dc=example,dc=com" || members[0].DisplayName != "Alice Liddell" || members[1].Resolved {
+		t.Errorf("unexpected posix members %+v", members)
+	}
+	if _, err := groups.Members("uid=bob,ou=person,dc=example,dc=com"); err == nil {
+		t.Error("expect error for an entry that is no group")
+	}
+}
+
+func TestGroupMembership(t *testing.T) {
+	dir := groupDirectory()
+	groups := &Groups{Op: dir, Base: "dc=example,dc=com", EmptyMember: "cn=nobody,dc=example,dc=com"}
+
+	// a uid is resolved to the DN, members already present are skipped
+	if err := groups.AddMembers("cn=admins,ou=group,dc=example,dc=com", []string{"bob", "UID=alice,ou=person,dc=example,dc=com"}); err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"add member uid=bob,ou=person,dc=example,dc=com"}; !slices.Equal(dir.changes, expect) {
+		t.Errorf("get changes %v, expect %v", dir.changes, expect)
+	}
+
+	// both attributes of a hybrid group are kept in sync and the placeholder goes away
+	dir.changes = nil
+	if err := groups.AddMembers("cn=dev,ou=group,dc=example,dc=com", []string{"uid=alice,ou=person,dc=example,dc=com"}); err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"add member uid=alice,ou=person,dc=example,dc=com",
+		"delete member cn=nobody,dc=example,dc=com",
+		"add memberUid alice",
+	}
+	if !slices.Equal(dir.changes, expect) {
+		t.Errorf("get changes %v, expect %v", dir.changes, expect)
+	}
+
+	// removing the last member of a groupOfNames puts the placeholder back
+	dir.changes = nil
+	if err := groups.RemoveMembers("cn=dev,ou=group,dc=example,dc=com", []string{"alice"}); err != nil {
+		t.Fatal(err)
+	}
+	expect = []string{
+		"add member cn=nobody,dc=example,dc=com",
+		"delete member uid=alice,ou=person,dc=example,dc=com",
+		"delete memberUid alice",
+	}
+	if !slices.Equal(dir.changes, expect) {
+		t.Errorf("get changes %v, expect %v", dir.changes, expect)
+	}
+
+	// uniqueMember values with an optional uid are matched by their DN
+	dir.changes = nil
+	if err := groups.RemoveMembers("cn=staff,ou=group,dc=example,dc=com", []string{"uid=bob,ou=person,dc=example,dc=com"}); err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"delete uniqueMember uid=bob,ou=person,dc=example,dc=com#'0101'B"}; !slices.Equal(dir.changes, expect) {
+		t.Errorf("get changes %v, expect %v", dir.changes, expect)
+	}
+
+	// posixGroup may become empty
+	dir.changes = nil
+	if err := groups.RemoveMembers("cn=users,ou=group,dc=example,dc=com", []string{"alice", "carol"}); err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"delete memberUid alice|carol"}; !slices.Equal(dir.changes, expect) {
+		t.Errorf("get changes %v, expect %v", dir.changes, expect)
+	}
+
+	// a placeholder stored next to real members is not added again
+	dir.entries["cn=ops,ou=group,dc=example,dc=com"] = map[string][]string{"objectClass": {"groupOfNames"}, "cn": {"ops"},
+		"member": {"cn=nobody,dc=example,dc=com", "uid=alice,ou=person,dc=example,dc=com"}}
+	dir.changes = nil
+	if err := groups.RemoveMembers("cn=ops,ou=group,dc=example,dc=com", []string{"alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"delete member uid=alice,ou=person,dc=example,dc=com"}; !slices.Equal(dir.changes, expect) {
+		t.Errorf("get changes %v, expect %v", dir.changes, expect)
+	}
+
+	groups.EmptyMember = ""
+	err := groups.RemoveMembers("cn=admins,ou=group,dc=example,dc=com", []string{"alice", "bob"})
+	if !errors.Is(err, ErrLastMember) {
+		t.Errorf("expect ErrLastMember, get %v", err)
+	}
+	if err := groups.AddMembers("cn=admins,ou=group,dc=example,dc=com", []string{"nobody"}); err == nil {
+		t.Error("expect error for an unknown uid")
+	}
+}
+
+func TestMemberships(t *testing.T) {
+	dir := groupDirectory()
+	// alice -> admins -> it -> all -> admins
+	dir.entries["cn=admins,ou=group,dc=example,dc=com"]["member"] = []string{"uid=alice,ou=person,dc=example,dc=com", "cn=all,ou=group,dc=example,dc=com"}
+	dir.entries["cn=it,ou=group,dc=example,dc=com"] = map[string][]string{"objectClass": {"groupOfNames"}, "cn": {"it"}, "member": {"cn=admins,ou=group,dc=example,dc=com"}}
+	dir.entries["cn=all,ou=group,dc=example,dc=com"] = map[string][]string{"objectClass": {"groupOfUniqueNames"}, "cn": {"all"}, "uniqueMember": {"cn=it,ou=group,dc=example,dc=com"}}
+	dir.entries["uid=alice,ou=person,dc=example,dc=com"]["memberOf"] = []string{"cn=admins,ou=group,dc=example,dc=com"}
+	dir.entries["cn=admins,ou=group,dc=example,dc=com"]["memberOf"] = []string{"cn=it,ou=group,dc=example,dc=com"}
+	dir.entries["cn=it,ou=group,dc=example,dc=com"]["memberOf"] = []string{"cn=all,ou=group,dc=example,dc=com"}
+	dir.entries["cn=all,ou=group,dc=example,dc=com"]["memberOf"] = []string{"cn=admins,ou=group,dc=example,dc=com"}
+	groups := &Groups{Op: dir, Base: "dc=example,dc=com"}
+
+	expect := []string{"admins:admins", "users:users", "it:admins>it", "all:admins>it>all"}
+	for _, method := range []string{MembershipWalk, MembershipMemberOf, MembershipInChain} {
+		graph, err := groups.Memberships("uid=alice,ou=person,dc=example,dc=com", method)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		var got []string
+		for _, membership := range graph.Groups {
+			var path []string
+			for _, dn := range membership.Path {
+				path = append(path, RDNValue(dn))
+			}
+			got = append(got, membership.Name+":"+strings.Join(path, ">"))
+		}
+		if !slices.Equal(got, expect) {
+			t.Errorf("%s: get memberships %v, expect %v", method, got, expect)
+		}
+		if !graph.Groups[0].Direct || !graph.Groups[1].Direct || graph.Groups[2].Direct {
+			t.Errorf("%s: unexpected direct flags %+v", method, graph.Groups)
+		}
+		if len(graph.Edges) != 5 {
+			t.Errorf("%s: expect 5 edges, get %v", method, graph.Edges)
+		}
+		if len(graph.Cycles) != 1 || len(graph.Cycles[0]) != 4 || !SameDN(graph.Cycles[0][3], "cn=admins,ou=group,dc=example,dc=com") {
+			t.Errorf("%s: expect the cycle admins > it > all > admins, get %v", method, graph.Cycles)
+		}
+	}
+
+	// the uid of the entry counts, not the RDN
+	dir.entries["cn=John Doe,ou=person,dc=example,dc=com"] = map[string][]string{"objectClass": {"inetOrgPerson", "posixAccount"}, "cn": {"John Doe"}, "uid": {"jdoe"}}
+	dir.entries["cn=users,ou=group,dc=example,dc=com"]["memberUid"] = []string{"alice", "jdoe"}
+	for _, method := range []string{MembershipWalk, MembershipMemberOf, MembershipInChain} {
+		graph, err := groups.Memberships("cn=John Doe,ou=person,dc=example,dc=com", method)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		if len(graph.Groups) != 1 || graph.Groups[0].Name != "users" {
+			t.Errorf("%s: expect the posixGroup users, get %+v", method, graph.Groups)
+		}
+	}
+
+	// detected from the memberOf values of the entry, an entry without them is walked
+	graph, err := groups.Memberships("uid=alice,ou=person,dc=example,dc=com", "")
+	if err != nil || graph.Method != MembershipMemberOf {
+		t.Errorf("expect memberOf for an entry with values, get %v %v", graph, err)
+	}
+	graph, err = groups.Memberships("cn=John Doe,ou=person,dc=example,dc=com", "")
+	if err != nil || graph.Method != MembershipWalk || len(graph.Groups) != 1 {
+		t.Errorf("expect a walk for an entry without memberOf, get %v %v", graph, err)
+	}
+	groups.MemberOf = MemberOfOff
+	if graph, err = groups.Memberships("uid=alice,ou=person,dc=example,dc=com", ""); err != nil || graph.Method != MembershipWalk {
+		t.Errorf("expect a walk when memberOf is off, get %v %v", graph, err)
+	}
+	groups.MemberOf = MemberOfOn
+	if graph, err = groups.Memberships("cn=John Doe,ou=person,dc=example,dc=com", ""); err != nil || graph.Method != MembershipMemberOf {
+		t.Errorf("expect memberOf when it is on, get %v %v", graph, err)
+	}
+	if _, err := groups.Memberships("uid=alice,ou=person,dc=example,dc=com", "guess"); err == nil {
+		t.Error("expect error for an unknown method")
+	}
+
+	for input, expect := range map[string]string{"": "", "auto": "", "ON": MemberOfOn, "off": MemberOfOff} {
+		if value, err := ParseMemberOf(input); err != nil || value != expect {
+			t.Errorf("ParseMemberOf(%q) = %q, %v, expect %q", input, value, err, expect)
+		}
+	}
+	if _, err := ParseMemberOf("sometimes"); err == nil {
+		t.Error("expect error for an unknown memberOf setting")
+	}
+}
@@ -1,10 +1,15 @@
 package ldap
 
 import (
+	"crypto/tls"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	gldap "github.com/go-ldap/ldap/v3"
 )
@@ -13,10 +18,16 @@ type LdapOperation interface{
 	Connect() error
 	Authenicate() error
 	Search(baseDN, filter string) ([]*gldap.Entry, error)
+	SearchPaged(opts SearchOptions) (*SearchPage, error)
 	GetAttrOfObjectClass(dn string) ([]*gldap.Entry, error) 
 	GetObjectClassAttributes() error
 	DeleteRecord(dn string) error
-	AddRecord(info map[string]string) error
+	DeleteTree(dn string, dryRun bool) ([]string, error)
+	AddRecord(record map[string][]string) error
+	AddEntry(dn string, attrs map[string][]string) error
+	ModifyRecord(dn string, changes []AttributeChange) error
+	RenameRecord(dn, newRDN, newSuperior string, deleteOldRDN bool) (string, error)
+	ChangePassword(dn, oldPassword, newPassword string) (string, error)
 	Close() error
 }
 
@@ -27,38 +38,43 @@ type LDAPOperation struct {
 	Pwd  string
 	Host string
 	Port int
-    ObjParser *ObjectClassParser
+	Transport TransportOptions
+	Profile ServerProfile
+	Schemas *SchemaCache	// shared between sessions of the same server
+	schema atomic.Pointer[ObjectClassParser]	// immutable snapshot, swapped on reload
+	schemaSource schemaReader	// reads the subschema instead of Conn when set
+	mu sync.Mutex	// guards rootDSE and the discovered base DN
+	rootDSE *RootDSE
+	BindPolicy *PolicyResponse	// password policy state reported by the bind, nil when none
 }
 
+// NewLDAPOperation prepares an operation with the default server profile, the
+// bind DN of user is resolved against the profile on Connect.
 func NewLDAPOperation(user, pwd, host string, port int) (*LDAPOperation, error) {
-	originUser := user
-	if user == "admin" {
-		user = "cn=admin,dc=example,dc=com"
-	}else if !strings.HasPrefix(user, "cn=") {
-		user = fmt.Sprint("uid=", user, ",ou=person,dc=example,dc=com")
-	}
-
 	ldapOperation := LDAPOperation{
 		User: user,
-		OriginUser: originUser,
+		OriginUser: user,
 		Pwd:  pwd,
 		Host: host,
 		Port: port,
-		ObjParser: NewObjectClassParser(),
+		Profile: DefaultServerProfile(),
+		Schemas: NewSchemaCache(),
 	}
 
 	return &ldapOperation, nil
 }
 
 func (op *LDAPOperation) Connect() error {
-	ldapUrl := fmt.Sprint("ldap://", op.Host, ":", op.Port)
-	conn, err := gldap.DialURL(ldapUrl)
+	conn, err := op.dial()
 	if err != nil {
 		return err
 	}
 	op.Conn = conn
 
-	err = op.Conn.Bind(op.User, op.Pwd)
+	if op.User, err = op.resolveBindDN(); err != nil {
+		return err
+	}
+	op.BindPolicy, err = bindWithPolicy(op.Conn, op.User, op.Pwd)
 	if err != nil {
 		return err
 	}
@@ -67,15 +83,46 @@ func (op *LDAPOperation) Connect() error {
 	return nil
 }
 
+// dial opens a new connection with the configured transport, not bound yet.
+func (op *LDAPOperation) dial() (*gldap.Conn, error) {
+	mode, err := ParseTransportMode(op.Transport.Mode)
+	if err != nil {
+		return nil, err
+	}
+	op.Transport.Mode = mode
+
+	var opts []gldap.DialOpt
+	var tlsConfig *tls.Config
+	if mode != TransportPlain {
+		if tlsConfig, err = op.Transport.TLSConfig(op.Host); err != nil {
+			return nil, err
+		}
+		opts = append(opts, gldap.DialWithTLSConfig(tlsConfig))
+	}
+
+	ldapUrl := fmt.Sprint(op.Transport.Scheme(), "://", op.Host, ":", op.Port)
+	conn, err := gldap.DialURL(ldapUrl, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == TransportStartTLS {
+		if err = conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("StartTLS failed: %w", err)
+		}
+	}
+	return conn, nil
+}
+
 func (op *LDAPOperation) Authenicate() error {
 	if op.Conn == nil {
 		return errors.New("LDAP connection is not established")
 	}
-	var dn string
-	if strings.Contains(op.User, "cn=admin") {
-		dn = "dc=example,dc=com"
-	}else{
-		dn = op.User
+	// the admin may be a rootdn without an entry, check the naming context instead
+	dn := op.User
+	if adminDN, err := op.AdminDN(); err == nil && strings.EqualFold(adminDN, op.User) {
+		dn, _ = op.BaseDN()
 	}
 	req:= gldap.NewSearchRequest(
 		dn,
@@ -91,28 +138,78 @@ func (op *LDAPOperation) Authenicate() error {
 	return err
 }
 
-func (op *LDAPOperation) AddRecord(info map[string]string) error {
-	dn,ok := info["DN"]
-	if !ok {
-		msg := "invlid request. missing dn attribute"
-		log.Fatalln()
-		return errors.New(msg)
+// AddRecord creates the entry described by record: the "DN" key holds the
+// DN, every other key an attribute with its values. Like in LDIF a key
+// ending in "::" carries base64 encoded values, e.g. "jpegPhoto::".
+func (op *LDAPOperation) AddRecord(record map[string][]string) error {
+	dn, attrs, err := decodeRecord(record)
+	if err != nil {
+		log.Println(err)
+		return err
 	}
-	delete(info, "DN")
-	addrequest := gldap.NewAddRequest(dn, nil)
-	for k, v := range info {
-		if strings.Contains(v, ",") {
-			vals := strings.Split(v, ",")
-			for i:=range vals{
-				vals[i] = strings.TrimSpace(vals[i])
+	return op.AddEntry(dn, attrs)
+}
+
+func decodeRecord(record map[string][]string) (string, map[string][]string, error) {
+	var dn string
+	attrs := make(map[string][]string, len(record))
+	for k, v := range record {
+		if k == "DN" {
+			if len(v) != 1 {
+				return "", nil, errors.New("invlid request. dn must have exactly one value")
 			}
-			addrequest.Attribute(k,vals)
-			
-		}else{
-			addrequest.Attribute(k,[]string{strings.TrimSpace(v)})
+			dn = v[0]
+			continue
 		}
-	}	
-	
+		name, encoded := strings.CutSuffix(k, "::")
+		values := v
+		if encoded {
+			values = make([]string, len(v))
+			for i, item := range v {
+				value, err := base64.StdEncoding.DecodeString(item)
+				if err != nil {
+					return "", nil, fmt.Errorf("invalid base64 value of %s: %w", name, err)
+				}
+				values[i] = string(value)
+			}
+		}
+		attrs[name] = append(attrs[name], values...)
+	}
+	if dn == "" {
+		return "", nil, errors.New("invlid request. missing dn attribute")
+	}
+	return dn, attrs, nil
+}
+
+// SplitLegacyValue splits a value of the old map[string]string add format at
+// commas. It corrupts values containing commas, e.g. DNs of member or
+// seeAlso, and is only used when legacy splitting is enabled.
+func SplitLegacyValue(value string) []string {
+	vals := strings.Split(value, ",")
+	for i := range vals {
+		vals[i] = strings.TrimSpace(vals[i])
+	}
+	return vals
+}
+
+// AddEntry creates dn with the given attribute values, values are sent unchanged.
+func (op *LDAPOperation) AddEntry(dn string, attrs map[string][]string) error {
+	if op.Conn == nil {
+		return errors.New("LDAP connection is not established")
+	}
+	if dn == "" {
+		return errors.New("please give an valid dn")
+	}
+	if schema := op.Schema(); schema.Loaded() && !op.Profile.SkipValidation {
+		if errs := schema.ValidateEntry(attrs); len(errs) > 0 {
+			return errs
+		}
+	}
+	addrequest := gldap.NewAddRequest(dn, nil)
+	for k, v := range attrs {
+		addrequest.Attribute(k, v)
+	}
+
 	if err := op.Conn.Add(addrequest); err != nil {
 		log.Println("add request error: ", err)
 		return err
@@ -120,6 +217,149 @@ func (op *LDAPOperation) AddRecord(info map[string]string) error {
 	return nil
 }
 
+// operations of an AttributeChange
+const (
+	ModifyAdd = "add"
+	ModifyReplace = "replace"
+	ModifyDelete = "delete"
+)
+
+// AttributeChange is one step of a modify. Delete without values removes the
+// whole attribute, with values only those values are removed.
+type AttributeChange struct {
+	Operation string `json:"op"`
+	Attribute string `json:"attribute"`
+	Values []string `json:"values"`
+}
+
+// ValidateChanges checks the operations of the changes before they are sent.
+func ValidateChanges(changes []AttributeChange) error {
+	if len(changes) == 0 {
+		return errors.New("no change to apply")
+	}
+	for _, change := range changes {
+		if change.Attribute == "" {
+			return errors.New("attribute name of a change is empty")
+		}
+		switch strings.ToLower(change.Operation) {
+		case ModifyAdd:
+			if len(change.Values) == 0 {
+				return fmt.Errorf("add on %s needs at least one value", change.Attribute)
+			}
+		case ModifyReplace, ModifyDelete:
+		default:
+			return fmt.Errorf("unknown modify operation %q on %s", change.Operation, change.Attribute)
+		}
+	}
+	return nil
+}
+
+func (op *LDAPOperation) ModifyRecord(dn string, changes []AttributeChange) error {
+	if op.Conn == nil {
+		return errors.New("LDAP connection is not established")
+	}
+	if dn == "" {
+		return errors.New("please give an valid dn")
+	}
+	if err := ValidateChanges(changes); err != nil {
+		return err
+	}
+	if op.Schema().Loaded() && !op.Profile.SkipValidation {
+		if err := op.validateModify(dn, changes); err != nil {
+			return err
+		}
+	}
+	modifyReq := gldap.NewModifyRequest(dn, nil)
+	for _, change := range changes {
+		switch strings.ToLower(change.Operation) {
+		case ModifyAdd:
+			modifyReq.Add(change.Attribute, change.Values)
+		case ModifyReplace:
+			modifyReq.Replace(change.Attribute, change.Values)
+		case ModifyDelete:
+			modifyReq.Delete(change.Attribute, change.Values)
+		}
+	}
+
+	if err := op.Conn.Modify(modifyReq); err != nil {
+		log.Println("modify request error: ", err)
+		return err
+	}
+	return nil
+}
+
+// validateModify validates the changed attributes against the current entry.
+func (op *LDAPOperation) validateModify(dn string, changes []AttributeChange) error {
+	entries, err := op.GetAttrOfObjectClass(dn)
+	if err != nil {
+		return err
+	}
+	if errs := op.Schema().ValidateModify(EntryAttributes(entries[0]), changes); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// RenameRecord changes the RDN of dn and, when newSuperior is given, moves it
+// below newSuperior. It returns the new DN of the entry.
+func (op *LDAPOperation) RenameRecord(dn, newRDN, newSuperior string, deleteOldRDN bool) (string, error) {
+	if op.Conn == nil {
+		return "", errors.New("LDAP connection is not established")
+	}
+	if dn == "" {
+		return "", errors.New("please give an valid dn")
+	}
+	parent, err := ParentDN(dn)
+	if err != nil {
+		return "", fmt.Errorf("invalid dn %s: %w", dn, err)
+	}
+	if newRDN == "" {
+		// a pure move keeps the current rdn
+		newRDN, _ = FirstRDN(dn)
+	}
+	if rdn, err := gldap.ParseDN(newRDN); err != nil || len(rdn.RDNs) != 1 {
+		return "", fmt.Errorf("invalid rdn %s", newRDN)
+	}
+	if newSuperior != "" {
+		if SameDN(newSuperior, parent) {
+			newSuperior = ""
+		} else if err := op.checkEntryExists(newSuperior); err != nil {
+			return "", fmt.Errorf("target parent %s: %w", newSuperior, err)
+		}
+	}
+
+	modDNReq := gldap.NewModifyDNRequest(dn, newRDN, deleteOldRDN, newSuperior)
+	if err := op.Conn.ModifyDN(modDNReq); err != nil {
+		log.Println("modify dn request error: ", err)
+		if gldap.IsErrorAnyOf(err, gldap.LDAPResultNotAllowedOnNonLeaf, gldap.LDAPResultAffectsMultipleDSAs) {
+			return "", fmt.Errorf("server refuses to rename %s because it has children, subtree rename is not supported: %w", dn, err)
+		}
+		return "", err
+	}
+	if newSuperior != "" {
+		parent = newSuperior
+	}
+	return JoinDN(newRDN, parent), nil
+}
+
+// checkEntryExists reads dn with a base search.
+func (op *LDAPOperation) checkEntryExists(dn string) error {
+	searchRequest := gldap.NewSearchRequest(
+		dn,
+		gldap.ScopeBaseObject,
+		gldap.NeverDerefAliases,
+		1, 0, false,
+		"(objectClass=*)",
+		[]string{"1.1"},
+		nil,
+	)
+	_, err := op.Conn.Search(searchRequest)
+	if gldap.IsErrorWithCode(err, gldap.LDAPResultNoSuchObject) {
+		return errors.New("entry does not exist")
+	}
+	return err
+}
+
 func (op *LDAPOperation) DeleteRecord(dn string) error {
 	if dn == "" || len(dn) <= 0{
 		return errors.New("please give an valid dn")
@@ -127,12 +367,90 @@ func (op *LDAPOperation) DeleteRecord(dn string) error {
 	delReq := gldap.NewDelRequest(dn, nil)
 
 	if err:=op.Conn.Del(delReq); err != nil{
-		log.Fatalln("delete record error:", err)
+		log.Println("delete record error:", err)
 		return err
 	}
 	return nil
 }
 
+// DeleteTree removes dn with everything below it and returns the removed DNs,
+// children first. The Tree Delete control is used when the server supports it,
+// otherwise the entries are deleted one by one. With dryRun nothing is deleted.
+func (op *LDAPOperation) DeleteTree(dn string, dryRun bool) ([]string, error) {
+	if op.Conn == nil {
+		return nil, errors.New("LDAP connection is not established")
+	}
+	if dn == "" {
+		return nil, errors.New("please give an valid dn")
+	}
+	dns, err := op.subtreeDNs(dn)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return dns, nil
+	}
+
+	if dse, err := op.RootDSE(); err == nil && dse.SupportsControl(gldap.ControlTypeSubtreeDelete) {
+		delReq := gldap.NewDelRequest(dn, []gldap.Control{gldap.NewControlSubtreeDelete()})
+		if err := op.Conn.Del(delReq); err != nil {
+			log.Println("tree delete error:", err)
+			return nil, err
+		}
+		return dns, nil
+	}
+
+	for i, item := range dns {
+		if err := op.Conn.Del(gldap.NewDelRequest(item, nil)); err != nil {
+			log.Println("delete record error:", item, err)
+			return dns[:i], fmt.Errorf("delete %s: %w", item, err)
+		}
+	}
+	return dns, nil
+}
+
+// subtreeDNs lists dn and its descendants page by page, deepest entries first.
+func (op *LDAPOperation) subtreeDNs(dn string) ([]string, error) {
+	searchRequest := gldap.NewSearchRequest(
+		dn,
+		gldap.ScopeWholeSubtree,
+		gldap.NeverDerefAliases,
+		0, 0, false,
+		"(objectClass=*)",
+		[]string{"1.1"},
+		nil,
+	)
+	// large subtrees exceed the size limit of a single search
+	result, err := op.Conn.SearchWithPaging(searchRequest, DefaultPageSize)
+	if err != nil {
+		return nil, err
+	}
+	dns := make([]string, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		dns = append(dns, entry.DN)
+	}
+	return SortChildrenFirst(dns), nil
+}
+
+// SortChildrenFirst orders DNs so every entry comes before its parent.
+func SortChildrenFirst(dns []string) []string {
+	depth := make(map[string]int, len(dns))
+	for _, dn := range dns {
+		if parsed, err := gldap.ParseDN(dn); err == nil {
+			depth[dn] = len(parsed.RDNs)
+		} else {
+			depth[dn] = strings.Count(dn, ",") + 1
+		}
+	}
+	sort.SliceStable(dns, func(i, j int) bool {
+		if depth[dns[i]] != depth[dns[j]] {
+			return depth[dns[i]] > depth[dns[j]]
+		}
+		return strings.ToLower(dns[i]) < strings.ToLower(dns[j])
+	})
+	return dns
+}
+
 func (op *LDAPOperation) Search(baseDN, filter string) ([]*gldap.Entry, error) {
 	if op.Conn == nil {
 		return nil, gldap.NewError(gldap.LDAPResultUnavailable, errors.New("LDAP connection is not established"))
@@ -148,7 +466,8 @@ func (op *LDAPOperation) Search(baseDN, filter string) ([]*gldap.Entry, error) {
 		nil,
 	)
 
-	result, err := op.Conn.Search(searchRequest)
+	// fetch page by page so the server size limit does not cut the result
+	result, err := op.Conn.SearchWithPaging(searchRequest, DefaultPageSize)
 	if err != nil {
 		return nil, err
 	}
@@ -179,37 +498,33 @@ func (op *LDAPOperation) GetAttrOfObjectClass(dn string) ([]*gldap.Entry, error)
 	return result.Entries, nil
 }
 
+// GetObjectClassAttributes loads the subschema through the schema cache, it
+// is read again from the server when the subschema entry changed.
 func (op *LDAPOperation) GetObjectClassAttributes() error {
-	if op.Conn == nil {
-		return errors.New("LDAP connection is not established")
-	}
-	if len(op.ObjParser.Objects) > 0{
-		return nil
+	parser, err := op.Schemas.Load(op, false)
+	if parser != nil {
+		op.schema.Store(parser)
 	}
-	searchRequest := gldap.NewSearchRequest(
-		"cn=subschema",
-		gldap.ScopeBaseObject,
-		gldap.NeverDerefAliases,
-		0, 0, false,
-		"(objectClass=*)",
-		[]string{"objectClasses"},
-		nil,
-	)
-	result, err := op.Conn.Search(searchRequest)
-	if err != nil {
-		return err
-	}
-	if len(result.Entries) == 0 {
-		return errors.New("no schema entry found")
+	return err
+}
+
+// RefreshSchema reads the subschema again even if it did not change.
+func (op *LDAPOperation) RefreshSchema() error {
+	parser, err := op.Schemas.Load(op, true)
+	if parser != nil {
+		op.schema.Store(parser)
 	}
-	objectClasses := result.Entries[0].GetAttributeValues("objectClasses")
-	for _, item := range objectClasses {
-		if _, err := op.ObjParser.ParseObjectClass(item); err != nil {
-			log.Fatal("parse objectclass error: ", err, item)
-			return err
-		}
+	return err
+}
+
+// Schema returns the schema snapshot of the session, an empty schema until
+// one is loaded. A snapshot is never changed after it is published, so it
+// may be read while a reload is in progress.
+func (op *LDAPOperation) Schema() *ObjectClassParser {
+	if parser := op.schema.Load(); parser != nil {
+		return parser
 	}
-	return nil
+	return emptySchema
 }
 
 func (op *LDAPOperation) Close() error {
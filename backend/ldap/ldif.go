@@ -0,0 +1,106 @@
+package ldap
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"unicode/utf8"
+
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+// LDIFLineWidth is the column where the LDIF writer folds long lines.
+const LDIFLineWidth = 76
+
+// LDIFWriter writes entries as LDIF content records (RFC 2849).
+type LDIFWriter struct {
+	w       io.Writer
+	Width   int // fold lines longer than Width, 0 disables folding
+	started bool
+}
+
+func NewLDIFWriter(w io.Writer) *LDIFWriter {
+	return &LDIFWriter{w: w, Width: LDIFLineWidth}
+}
+
+// WriteEntry writes one record, the version line is written before the first record.
+func (l *LDIFWriter) WriteEntry(entry *gldap.Entry) error {
+	var buf bytes.Buffer
+	if !l.started {
+		buf.WriteString("version: 1\n")
+		l.started = true
+	}
+	buf.WriteString("\n")
+	l.writeLine(&buf, "dn", []byte(entry.DN))
+	for _, attr := range entry.Attributes {
+		values := attr.ByteValues
+		if len(values) == 0 {
+			// entries built by hand may only carry string values
+			for _, value := range attr.Values {
+				values = append(values, []byte(value))
+			}
+		}
+		for _, value := range values {
+			l.writeLine(&buf, attr.Name, value)
+		}
+	}
+	_, err := l.w.Write(buf.Bytes())
+	return err
+}
+
+// WriteComment writes a comment line, e.g. to report an error at the end of a stream.
+func (l *LDIFWriter) WriteComment(comment string) error {
+	var buf bytes.Buffer
+	buf.WriteString("\n")
+	l.fold(&buf, "# "+comment)
+	_, err := l.w.Write(buf.Bytes())
+	return err
+}
+
+func (l *LDIFWriter) writeLine(buf *bytes.Buffer, name string, value []byte) {
+	if IsLDIFSafe(value) {
+		l.fold(buf, name+": "+string(value))
+	} else {
+		l.fold(buf, name+":: "+base64.StdEncoding.EncodeToString(value))
+	}
+}
+
+// fold splits line in chunks of Width bytes, continuation lines start with a space.
+// Multi-byte characters are never split.
+func (l *LDIFWriter) fold(buf *bytes.Buffer, line string) {
+	width := l.Width
+	for width > 1 && len(line) > width {
+		cut := width
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		buf.WriteString(line[:cut])
+		buf.WriteString("\n ")
+		line = line[cut:]
+		// the leading space of a continuation line takes one column
+		width = l.Width - 1
+	}
+	buf.WriteString(line)
+	buf.WriteString("\n")
+}
+
+// IsLDIFSafe reports whether value can be written as a SAFE-STRING, all other
+// values have to be base64 encoded.
+func IsLDIFSafe(value []byte) bool {
+	if len(value) == 0 {
+		return true
+	}
+	switch value[0] {
+	case ' ', ':', '<':
+		return false
+	}
+	if value[len(value)-1] == ' ' {
+		return false
+	}
+	for _, b := range value {
+		if b == 0 || b == '\n' || b == '\r' || b > 127 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+// status of an imported record
+const (
+	ImportApplied = "applied"
+	ImportValid   = "valid" // dry run passed
+	ImportFailed  = "failed"
+	ImportSkipped = "skipped" // not tried because an earlier record failed
+)
+
+type ImportOptions struct {
+	ContinueOnError bool
+	DryRun          bool               // validate only, nothing is written
+	Schema          *ObjectClassParser // used by the dry run, may be nil
+}
+
+type ImportResult struct {
+	Line       int    `json:"line"`
+	DN         string `json:"dn"`
+	ChangeType string `json:"changeType"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ImportLDIF applies the records in order through op and reports one result
+// per record. Unless ContinueOnError is set the first failure stops the import.
+func ImportLDIF(op LdapOperation, records []*LDIFRecord, opts ImportOptions) []ImportResult {
+	results := make([]ImportResult, 0, len(records))
+	failed := false
+	for _, record := range records {
+		result := ImportResult{Line: record.Line, DN: record.DN, ChangeType: record.ChangeType}
+		if result.ChangeType == "" {
+			result.ChangeType = ChangeAdd
+		}
+
+		var err error
+		switch {
+		case failed && !opts.ContinueOnError:
+			result.Status = ImportSkipped
+			results = append(results, result)
+			continue
+		case opts.DryRun:
+			err = validateLDIFRecord(op, record, opts.Schema)
+		default:
+			err = applyLDIFRecord(op, record)
+		}
+
+		if err != nil {
+			failed = true
+			result.Status = ImportFailed
+			result.Error = err.Error()
+		} else if opts.DryRun {
+			result.Status = ImportValid
+		} else {
+			result.Status = ImportApplied
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func applyLDIFRecord(op LdapOperation, record *LDIFRecord) error {
+	switch record.ChangeType {
+	case "", ChangeAdd:
+		return op.AddEntry(record.DN, record.Attributes)
+	case ChangeDelete:
+		return op.DeleteRecord(record.DN)
+	case ChangeModify:
+		return op.ModifyRecord(record.DN, record.Changes)
+	case ChangeModRDN:
+		_, err := op.RenameRecord(record.DN, record.NewRDN, record.NewSuperior, record.DeleteOldRDN)
+		return err
+	}
+	return fmt.Errorf("unknown changetype %s", record.ChangeType)
+}
+
+// validateLDIFRecord checks a record without changing the server, only the
+// target of a modify is read to validate the changes against it.
+func validateLDIFRecord(op LdapOperation, record *LDIFRecord, schema *ObjectClassParser) error {
+	if _, err := gldap.ParseDN(record.DN); err != nil {
+		return fmt.Errorf("invalid dn: %w", err)
+	}
+	switch record.ChangeType {
+	case "", ChangeAdd:
+		return validateAgainstSchema(record.Attributes, schema)
+	case ChangeModify:
+		if err := ValidateChanges(record.Changes); err != nil {
+			return err
+		}
+		return validateModifyAgainstSchema(op, record, schema)
+	case ChangeModRDN:
+		if rdn, err := gldap.ParseDN(record.NewRDN); err != nil || len(rdn.RDNs) != 1 {
+			return fmt.Errorf("invalid newrdn %s", record.NewRDN)
+		}
+		if record.NewSuperior != "" {
+			if _, err := gldap.ParseDN(record.NewSuperior); err != nil {
+				return fmt.Errorf("invalid newsuperior: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateModifyAgainstSchema checks the changes of a modify record against
+// the stored entry. When it can not be read, e.g. an earlier record of the
+// same file adds it, only the changed attributes are checked.
+func validateModifyAgainstSchema(op LdapOperation, record *LDIFRecord, schema *ObjectClassParser) error {
+	if schema == nil || !schema.Loaded() {
+		return nil
+	}
+	var errs ValidationErrors
+	if entries, err := op.GetAttrOfObjectClass(record.DN); err == nil && len(entries) > 0 {
+		errs = schema.ValidateModify(EntryAttributes(entries[0]), record.Changes)
+	} else {
+		errs = schema.changeProblems(ApplyChanges(nil, record.Changes), record.Changes)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateAgainstSchema checks the attributes with the schema validator.
+// Without a loaded schema only the presence of objectClass is checked.
+func validateAgainstSchema(attrs map[string][]string, schema *ObjectClassParser) error {
+	if schema == nil || !schema.Loaded() {
+		for name := range attrs {
+			if strings.EqualFold(name, "objectClass") {
+				return nil
+			}
+		}
+		return errors.New("missing objectClass")
+	}
+	if errs := schema.ValidateEntry(attrs); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
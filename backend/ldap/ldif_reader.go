@@ -0,0 +1,275 @@
+package ldap
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// change types of an LDIF record, a content record has no change type
+const (
+	ChangeAdd    = "add"
+	ChangeModify = "modify"
+	ChangeDelete = "delete"
+	ChangeModRDN = "modrdn"
+)
+
+// LDIFRecord is one record of an LDIF file.
+type LDIFRecord struct {
+	Line       int                 `json:"line"`
+	DN         string              `json:"dn"`
+	ChangeType string              `json:"changeType,omitempty"`
+	Attributes map[string][]string `json:"attributes,omitempty"` // content and add records
+
+	Changes []AttributeChange `json:"changes,omitempty"` // modify records
+
+	NewRDN       string `json:"newRDN,omitempty"` // modrdn records
+	DeleteOldRDN bool   `json:"deleteOldRDN,omitempty"`
+	NewSuperior  string `json:"newSuperior,omitempty"`
+}
+
+// ldifLine is an unfolded line with the number of the line it started on.
+type ldifLine struct {
+	number int
+	text   string
+}
+
+// ParseLDIF reads content and change records (RFC 2849). URL values and
+// controls are not supported.
+func ParseLDIF(r io.Reader) ([]*LDIFRecord, error) {
+	blocks, err := readLDIFBlocks(r)
+	if err != nil {
+		return nil, err
+	}
+	var records []*LDIFRecord
+	for i, block := range blocks {
+		if i == 0 && strings.HasPrefix(strings.ToLower(block[0].text), "version:") {
+			version := strings.TrimSpace(block[0].text[len("version:"):])
+			if version != "1" {
+				return nil, fmt.Errorf("line %d: unsupported LDIF version %s", block[0].number, version)
+			}
+			block = block[1:]
+			if len(block) == 0 {
+				continue
+			}
+		}
+		record, err := parseLDIFRecord(block)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// readLDIFBlocks unfolds continuation lines, drops comments and splits the
+// input into records at blank lines.
+func readLDIFBlocks(r io.Reader) ([][]ldifLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var blocks [][]ldifLine
+	var block []ldifLine
+	inComment := false
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		switch {
+		case strings.HasPrefix(text, " "):
+			if inComment {
+				continue
+			}
+			if len(block) == 0 {
+				return nil, fmt.Errorf("line %d: continuation line without a preceding line", number)
+			}
+			block[len(block)-1].text += text[1:]
+		case strings.HasPrefix(text, "#"):
+			inComment = true
+		case text == "":
+			inComment = false
+			if len(block) > 0 {
+				blocks = append(blocks, block)
+				block = nil
+			}
+		default:
+			inComment = false
+			block = append(block, ldifLine{number: number, text: text})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(block) > 0 {
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// splitLDIFLine splits "name: value", "name:: base64" and rejects "name:< url".
+func splitLDIFLine(line ldifLine) (string, string, error) {
+	idx := strings.Index(line.text, ":")
+	if idx <= 0 {
+		return "", "", fmt.Errorf("line %d: expect \"attribute: value\", get %q", line.number, line.text)
+	}
+	name := line.text[:idx]
+	rest := line.text[idx+1:]
+	switch {
+	case strings.HasPrefix(rest, ":"):
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(rest[1:]))
+		if err != nil {
+			return "", "", fmt.Errorf("line %d: invalid base64 value of %s: %v", line.number, name, err)
+		}
+		return name, string(decoded), nil
+	case strings.HasPrefix(rest, "<"):
+		return "", "", fmt.Errorf("line %d: URL values are not supported", line.number)
+	}
+	return name, strings.TrimLeft(rest, " "), nil
+}
+
+func parseLDIFRecord(block []ldifLine) (*LDIFRecord, error) {
+	name, dn, err := splitLDIFLine(block[0])
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(name, "dn") {
+		return nil, fmt.Errorf("line %d: record must start with dn, get %s", block[0].number, name)
+	}
+	record := &LDIFRecord{Line: block[0].number, DN: dn}
+	lines := block[1:]
+
+	if len(lines) > 0 {
+		name, value, err := splitLDIFLine(lines[0])
+		if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(name, "control") {
+			return nil, fmt.Errorf("line %d: controls are not supported", lines[0].number)
+		}
+		if strings.EqualFold(name, "changetype") {
+			record.ChangeType = strings.ToLower(value)
+			lines = lines[1:]
+		}
+	}
+
+	switch record.ChangeType {
+	case "", ChangeAdd:
+		if len(lines) == 0 {
+			return nil, fmt.Errorf("line %d: entry %s has no attributes", record.Line, dn)
+		}
+		record.Attributes, err = parseLDIFAttributes(lines)
+	case ChangeDelete:
+		if len(lines) > 0 {
+			err = fmt.Errorf("line %d: delete record must not have attributes", lines[0].number)
+		}
+	case ChangeModify:
+		record.Changes, err = parseLDIFChanges(lines)
+	case ChangeModRDN, "moddn":
+		record.ChangeType = ChangeModRDN
+		err = parseLDIFModRDN(record, lines)
+	default:
+		err = fmt.Errorf("line %d: unknown changetype %s", record.Line, record.ChangeType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func parseLDIFAttributes(lines []ldifLine) (map[string][]string, error) {
+	attrs := make(map[string][]string)
+	for _, line := range lines {
+		name, value, err := splitLDIFLine(line)
+		if err != nil {
+			return nil, err
+		}
+		// attribute names are case insensitive, keep the first spelling
+		for key := range attrs {
+			if strings.EqualFold(key, name) {
+				name = key
+				break
+			}
+		}
+		attrs[name] = append(attrs[name], value)
+	}
+	return attrs, nil
+}
+
+func parseLDIFChanges(lines []ldifLine) ([]AttributeChange, error) {
+	if len(lines) == 0 {
+		return nil, errors.New("modify record without changes")
+	}
+	var changes []AttributeChange
+	var current *AttributeChange
+	for _, line := range lines {
+		if line.text == "-" {
+			if current == nil {
+				return nil, fmt.Errorf("line %d: unexpected -", line.number)
+			}
+			changes = append(changes, *current)
+			current = nil
+			continue
+		}
+		name, value, err := splitLDIFLine(line)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil {
+			operation := strings.ToLower(name)
+			switch operation {
+			case ModifyAdd, ModifyReplace, ModifyDelete:
+			default:
+				return nil, fmt.Errorf("line %d: unsupported modify operation %s", line.number, name)
+			}
+			current = &AttributeChange{Operation: operation, Attribute: value}
+			continue
+		}
+		if !strings.EqualFold(name, current.Attribute) {
+			return nil, fmt.Errorf("line %d: attribute %s does not match %s", line.number, name, current.Attribute)
+		}
+		current.Values = append(current.Values, value)
+	}
+	if current != nil {
+		// the trailing - of the last change is optional in practice
+		changes = append(changes, *current)
+	}
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("line %d: modify record without changes", lines[0].number)
+	}
+	return changes, nil
+}
+
+func parseLDIFModRDN(record *LDIFRecord, lines []ldifLine) error {
+	deleteOld := ""
+	for _, line := range lines {
+		name, value, err := splitLDIFLine(line)
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(name) {
+		case "newrdn":
+			record.NewRDN = value
+		case "deleteoldrdn":
+			deleteOld = value
+		case "newsuperior":
+			record.NewSuperior = value
+		default:
+			return fmt.Errorf("line %d: unexpected %s in modrdn record", line.number, name)
+		}
+	}
+	if record.NewRDN == "" {
+		return fmt.Errorf("line %d: modrdn record without newrdn", record.Line)
+	}
+	switch deleteOld {
+	case "0":
+		record.DeleteOldRDN = false
+	case "1":
+		record.DeleteOldRDN = true
+	default:
+		return fmt.Errorf("line %d: deleteoldrdn must be 0 or 1", record.Line)
+	}
+	return nil
+}
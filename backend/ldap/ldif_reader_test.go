@@ -0,0 +1,246 @@
+package ldap
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+const sampleLDIF = `version: 1
+
+# a content record
+dn: uid=alice,ou=person,dc=example,dc=com
+objectClass: top
+objectclass: inetOrgPerson
+cn: Alice
+sn: Liddell
+description: a long description that is
+  folded over two lines
+cn:: Wm/Dqw==
+
+dn: cn=admins,ou=group,dc=example,dc=com
+changetype: modify
+add: member
+member: uid=alice,ou=person,dc=example,dc=com
+-
+delete: description
+-
+replace: owner
+owner: uid=bob,ou=person,dc=example,dc=com
+-
+
+dn: uid=carl,ou=person,dc=example,dc=com
+changetype: delete
+
+dn: uid=dave,ou=person,dc=example,dc=com
+changetype: modrdn
+newrdn: uid=david
+deleteoldrdn: 1
+newsuperior: ou=staff,dc=example,dc=com
+`
+
+func TestParseLDIF(t *testing.T) {
+	records, err := ParseLDIF(strings.NewReader(sampleLDIF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("get %d records, expect 4", len(records))
+	}
+
+	add := records[0]
+	if add.ChangeType != "" || add.Line != 4 {
+		t.Errorf("unexpected content record: %+v", add)
+	}
+	if !slices.Equal(add.Attributes["objectClass"], []string{"top", "inetOrgPerson"}) {
+		t.Errorf("get objectClass: %v", add.Attributes["objectClass"])
+	}
+	if !slices.Equal(add.Attributes["cn"], []string{"Alice", "Zoë"}) {
+		t.Errorf("get cn: %v", add.Attributes["cn"])
+	}
+	if add.Attributes["description"][0] != "a long description that is folded over two lines" {
+		t.Errorf("get description: %q", add.Attributes["description"][0])
+	}
+
+	modify := records[1]
+	expect := []AttributeChange{
+		{Operation: ModifyAdd, Attribute: "member", Values: []string{"uid=alice,ou=person,dc=example,dc=com"}},
+		{Operation: ModifyDelete, Attribute: "description"},
+		{Operation: ModifyReplace, Attribute: "owner", Values: []string{"uid=bob,ou=person,dc=example,dc=com"}},
+	}
+	if len(modify.Changes) != len(expect) {
+		t.Fatalf("get changes: %+v", modify.Changes)
+	}
+	for i := range expect {
+		if modify.Changes[i].Operation != expect[i].Operation || modify.Changes[i].Attribute != expect[i].Attribute ||
+			!slices.Equal(modify.Changes[i].Values, expect[i].Values) {
+			t.Errorf("get change: %+v, expect: %+v", modify.Changes[i], expect[i])
+		}
+	}
+
+	if records[2].ChangeType != ChangeDelete {
+		t.Errorf("get changetype: %s, expect: delete", records[2].ChangeType)
+	}
+	modrdn := records[3]
+	if modrdn.NewRDN != "uid=david" || !modrdn.DeleteOldRDN || modrdn.NewSuperior != "ou=staff,dc=example,dc=com" {
+		t.Errorf("unexpected modrdn record: %+v", modrdn)
+	}
+}
+
+func TestParseLDIFErrors(t *testing.T) {
+	inputs := []string{
+		"cn: no dn first\n",
+		"dn: cn=a,dc=example,dc=com\nchangetype: rename\n",
+		"dn: cn=a,dc=example,dc=com\nchangetype: modify\nincrement: uidNumber\nuidNumber: 1\n",
+		"dn: cn=a,dc=example,dc=com\njpegPhoto:< file:///tmp/a.jpg\n",
+		"dn: cn=a,dc=example,dc=com\nchangetype: modrdn\nnewrdn: cn=b\n",
+		"version: 2\n\ndn: cn=a,dc=example,dc=com\ncn: a\n",
+	}
+	for _, input := range inputs {
+		if _, err := ParseLDIF(strings.NewReader(input)); err == nil {
+			t.Errorf("expect error for %q", input)
+		}
+	}
+}
+
+func TestLDIFRoundTrip(t *testing.T) {
+	entry := &gldap.Entry{
+		DN: "cn=Zoë,dc=example,dc=com",
+		Attributes: []*gldap.EntryAttribute{
+			{Name: "objectClass", Values: []string{"top", "person"}},
+			{Name: "description", Values: []string{strings.Repeat("long value ", 20)}},
+			{Name: "userCertificate;binary", Values: []string{"\x30\x82\x00\xff"}},
+		},
+	}
+	var buf bytes.Buffer
+	if err := NewLDIFWriter(&buf).WriteEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	records, err := ParseLDIF(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].DN != entry.DN {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+	for _, attr := range entry.Attributes {
+		if !slices.Equal(records[0].Attributes[attr.Name], attr.Values) {
+			t.Errorf("get %s: %q, expect: %q", attr.Name, records[0].Attributes[attr.Name], attr.Values)
+		}
+	}
+}
+
+// recordingOperation remembers the calls of the import and fails on demand.
+type recordingOperation struct {
+	LDAPOperation
+	calls  []string
+	failDN string
+}
+
+func (r *recordingOperation) call(name, dn string) error {
+	r.calls = append(r.calls, name+" "+dn)
+	if dn == r.failDN {
+		return gldap.NewError(gldap.LDAPResultNoSuchObject, errors.New("no such object"))
+	}
+	return nil
+}
+
+func (r *recordingOperation) AddEntry(dn string, attrs map[string][]string) error {
+	return r.call("add", dn)
+}
+
+func (r *recordingOperation) DeleteRecord(dn string) error {
+	return r.call("delete", dn)
+}
+
+func (r *recordingOperation) ModifyRecord(dn string, changes []AttributeChange) error {
+	return r.call("modify", dn)
+}
+
+func (r *recordingOperation) RenameRecord(dn, newRDN, newSuperior string, deleteOldRDN bool) (string, error) {
+	return "", r.call("modrdn", dn)
+}
+
+func TestImportLDIF(t *testing.T) {
+	records, err := ParseLDIF(strings.NewReader(sampleLDIF))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	op := &recordingOperation{failDN: "cn=admins,ou=group,dc=example,dc=com"}
+	results := ImportLDIF(op, records, ImportOptions{})
+	statuses := []string{}
+	for _, result := range results {
+		statuses = append(statuses, result.Status)
+	}
+	if !slices.Equal(statuses, []string{ImportApplied, ImportFailed, ImportSkipped, ImportSkipped}) {
+		t.Errorf("get statuses: %v", statuses)
+	}
+
+	op = &recordingOperation{failDN: "cn=admins,ou=group,dc=example,dc=com"}
+	results = ImportLDIF(op, records, ImportOptions{ContinueOnError: true})
+	if len(op.calls) != 4 || results[3].Status != ImportApplied {
+		t.Errorf("get calls: %v, results: %+v", op.calls, results)
+	}
+
+	op = &recordingOperation{}
+	results = ImportLDIF(op, records, ImportOptions{DryRun: true})
+	if len(op.calls) != 0 {
+		t.Errorf("dry run must not write, get calls: %v", op.calls)
+	}
+	for _, result := range results {
+		if result.Status != ImportValid {
+			t.Errorf("get status: %s, expect: valid for %s", result.Status, result.DN)
+		}
+	}
+}
+
+func TestImportLDIFDryRunModify(t *testing.T) {
+	schema := validatorSchema(t)
+	dir := &memoryDirectory{entries: map[string]map[string][]string{
+		"uid=john,ou=person,dc=example,dc=com": {"objectClass": {"inetOrgPerson"}, "cn": {"John"}, "sn": {"Doe"}, "uid": {"john"}},
+	}}
+	records, err := ParseLDIF(strings.NewReader(`dn: uid=john,ou=person,dc=example,dc=com
+changetype: modify
+replace: displayName
+displayName: John
+displayName: Johnny
+-
+
+dn: uid=john,ou=person,dc=example,dc=com
+changetype: modify
+delete: sn
+-
+
+dn: uid=jane,ou=person,dc=example,dc=com
+changetype: modify
+add: createTimestamp
+createTimestamp: 20240131235959Z
+-
+
+dn: uid=john,ou=person,dc=example,dc=com
+changetype: modify
+replace: description
+description: valid
+-
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := ImportLDIF(dir, records, ImportOptions{DryRun: true, ContinueOnError: true, Schema: schema})
+	statuses := []string{}
+	for _, result := range results {
+		statuses = append(statuses, result.Status)
+	}
+	// single value and a removed MUST against the stored entry, a missing entry gets the attribute checks
+	if !slices.Equal(statuses, []string{ImportFailed, ImportFailed, ImportFailed, ImportValid}) {
+		t.Errorf("get statuses: %v, results: %+v", statuses, results)
+	}
+	if len(dir.changes) != 0 {
+		t.Errorf("dry run must not write, get %v", dir.changes)
+	}
+}
@@ -0,0 +1,68 @@
+package ldap
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+func TestLDIFWriter(t *testing.T) {
+	longDesc := strings.Repeat("a", 100)
+	entry := &gldap.Entry{
+		DN: "uid=alice,ou=person,dc=example,dc=com",
+		Attributes: []*gldap.EntryAttribute{
+			{Name: "objectClass", ByteValues: [][]byte{[]byte("top"), []byte("inetOrgPerson")}},
+			{Name: "cn", ByteValues: [][]byte{[]byte("Zoë")}},
+			{Name: "description", ByteValues: [][]byte{[]byte(longDesc)}},
+			{Name: "title", ByteValues: [][]byte{[]byte(" leading space")}},
+			{Name: "jpegPhoto", ByteValues: [][]byte{{0xff, 0xd8, 0x00}}},
+			{Name: "sn", Values: []string{"Smith"}},
+		},
+	}
+	var buf bytes.Buffer
+	writer := NewLDIFWriter(&buf)
+	if err := writer.WriteEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	expect := "version: 1\n" +
+		"\n" +
+		"dn: uid=alice,ou=person,dc=example,dc=com\n" +
+		"objectClass: top\n" +
+		"objectClass: inetOrgPerson\n" +
+		"cn:: Wm/Dqw==\n" +
+		"description: " + longDesc[:63] + "\n" +
+		" " + longDesc[63:] + "\n" +
+		"title:: IGxlYWRpbmcgc3BhY2U=\n" +
+		"jpegPhoto:: /9gA\n" +
+		"sn: Smith\n"
+	if buf.String() != expect {
+		t.Errorf("get value:\n%s\nexpect:\n%s", buf.String(), expect)
+	}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if len(line) > LDIFLineWidth {
+			t.Errorf("line longer than %d: %s", LDIFLineWidth, line)
+		}
+	}
+}
+
+func TestIsLDIFSafe(t *testing.T) {
+	values := []struct {
+		value  string
+		expect bool
+	}{
+		{"", true},
+		{"plain value", true},
+		{":colon", false},
+		{"<url", false},
+		{"trailing ", false},
+		{"line\nbreak", false},
+		{"naïve", false},
+	}
+	for _, value := range values {
+		if IsLDIFSafe([]byte(value.value)) != value.expect {
+			t.Errorf("value %q: expect safe=%v", value.value, value.expect)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package ldap
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+// methods used to resolve memberships
+const (
+	MembershipMemberOf = "memberOf" // memberOf of the user and its groups
+	MembershipInChain  = "in-chain" // one search with LDAP_MATCHING_RULE_IN_CHAIN
+	MembershipWalk     = "walk"     // search the groups containing each member
+)
+
+// whether the server maintains memberOf, e.g. with the OpenLDAP memberof overlay
+const (
+	MemberOfOn  = "on"
+	MemberOfOff = "off"
+)
+
+// ParseMemberOf normalizes the memberOf setting, empty means detect it from the entries.
+func ParseMemberOf(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "auto":
+		return "", nil
+	case MemberOfOn, "true", "yes":
+		return MemberOfOn, nil
+	case MemberOfOff, "false", "no":
+		return MemberOfOff, nil
+	}
+	return "", fmt.Errorf("unknown memberOf setting %q, expect one of auto, on, off", value)
+}
+
+const (
+	// InChainOID is the matching rule of Active Directory following nested membership.
+	InChainOID = "1.2.840.113556.1.4.1941"
+	// ActiveDirectoryCapability is published in supportedCapabilities of Active Directory.
+	ActiveDirectoryCapability = "1.2.840.113556.1.4.800"
+)
+
+// Membership is a group the user belongs to, Path lists the groups from the
+// direct group to this one along the shortest way.
+type Membership struct {
+	DN     string   `json:"dn"`
+	Name   string   `json:"name"`
+	Direct bool     `json:"direct"`
+	Path   []string `json:"path"`
+}
+
+// MembershipEdge says Member is a member of Group.
+type MembershipEdge struct {
+	Member string `json:"member"`
+	Group  string `json:"group"`
+}
+
+type MembershipGraph struct {
+	DN     string           `json:"dn"`
+	Method string           `json:"method"`
+	Groups []Membership     `json:"groups"`
+	Edges  []MembershipEdge `json:"edges"`
+	Cycles [][]string       `json:"cycles,omitempty"` // group chains leading back to themselves
+}
+
+// Memberships returns the groups dn belongs to directly and through nested
+// groups. method forces one of the Membership* methods, empty picks the best
+// the server supports: in-chain on Active Directory, memberOf when it is
+// switched on or, when detected, dn has memberOf values, otherwise a walk over
+// the member attributes of the groups.
+func (g *Groups) Memberships(dn, method string) (*MembershipGraph, error) {
+	entries, err := g.Op.GetAttrOfObjectClass(dn)
+	if err != nil {
+		return nil, err
+	}
+	uid := entries[0].GetAttributeValue("uid")
+
+	if method == "" {
+		switch {
+		case g.InChain:
+			method = MembershipInChain
+		case g.MemberOf == MemberOfOn:
+			method = MembershipMemberOf
+		case g.MemberOf == "" && g.maintainsMemberOf(dn):
+			method = MembershipMemberOf
+		default:
+			method = MembershipWalk
+		}
+	}
+	var parents func(string) ([]*Group, error)
+	switch method {
+	case MembershipMemberOf:
+		parents = g.memberOfParents
+	case MembershipInChain:
+		if parents, err = g.inChainParents(dn); err != nil {
+			return nil, err
+		}
+	case MembershipWalk:
+		parents = func(member string) ([]*Group, error) {
+			// only the starting entry is an account, its uid need not be part of the RDN
+			if SameDN(member, dn) {
+				return g.walkParents(member, uid)
+			}
+			return g.walkParents(member, "")
+		}
+	default:
+		return nil, fmt.Errorf("unknown membership method %q, expect one of memberOf, in-chain, walk", method)
+	}
+
+	// memberOf and in-chain only follow DN valued members, add the posixGroups of the uid
+	direct := parents
+	if method != MembershipWalk && uid != "" {
+		direct = func(member string) ([]*Group, error) {
+			groups, err := parents(member)
+			if err != nil || !SameDN(member, dn) {
+				return groups, err
+			}
+			posix, err := g.containing("(memberUid="+gldap.EscapeFilter(uid)+")", MemberUIDAttribute, uid)
+			return append(groups, posix...), err
+		}
+	}
+	return resolveMemberships(dn, method, func(member string) ([]*Group, error) {
+		if SameDN(member, dn) {
+			return direct(member)
+		}
+		return parents(member)
+	})
+}
+
+// resolveMemberships walks breadth first from dn to the groups returned by
+// parents, each group is visited once so cycles end the walk.
+func resolveMemberships(dn, method string, parents func(string) ([]*Group, error)) (*MembershipGraph, error) {
+	graph := &MembershipGraph{DN: dn, Method: method, Groups: []Membership{}, Edges: []MembershipEdge{}}
+	paths := map[string][]string{DNKey(dn): {}}
+	queue := []string{dn}
+	for len(queue) > 0 {
+		member := queue[0]
+		queue = queue[1:]
+		groups, err := parents(member)
+		if err != nil {
+			return nil, err
+		}
+		path := paths[DNKey(member)]
+		for _, group := range groups {
+			key := DNKey(group.DN)
+			graph.Edges = append(graph.Edges, MembershipEdge{Member: member, Group: group.DN})
+			if _, seen := paths[key]; seen {
+				// a group already on the way to member closes a cycle
+				if at := slices.IndexFunc(path, func(item string) bool { return DNKey(item) == key }); at >= 0 {
+					graph.Cycles = append(graph.Cycles, append(slices.Clone(path[at:]), group.DN))
+				}
+				continue
+			}
+			groupPath := append(slices.Clone(path), group.DN)
+			paths[key] = groupPath
+			graph.Groups = append(graph.Groups, Membership{DN: group.DN, Name: group.Name, Direct: len(groupPath) == 1, Path: groupPath})
+			queue = append(queue, group.DN)
+		}
+	}
+	return graph, nil
+}
+
+// maintainsMemberOf reports whether dn carries memberOf values. A schema
+// defining memberOf proves nothing, 389 Directory Server always defines it and
+// OpenLDAP may load it without the overlay, so an entry without values is
+// resolved by the walk.
+func (g *Groups) maintainsMemberOf(dn string) bool {
+	groups, err := g.memberOfParents(dn)
+	return err == nil && len(groups) > 0
+}
+
+// memberOfParents reads the memberOf values of member.
+func (g *Groups) memberOfParents(member string) ([]*Group, error) {
+	page, err := g.Op.SearchPaged(SearchOptions{
+		BaseDN:     member,
+		Scope:      "base",
+		Filter:     "(objectClass=*)",
+		Attributes: []string{"memberOf"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var groups []*Group
+	for _, entry := range page.Entries {
+		for _, dn := range entry.GetEqualFoldAttributeValues("memberOf") {
+			groups = append(groups, &Group{DN: dn, Name: RDNValue(dn)})
+		}
+	}
+	return groups, nil
+}
+
+// inChainParents finds all groups of dn with one search and answers the
+// parents of a member from that set, so paths can still be reported.
+func (g *Groups) inChainParents(dn string) (func(string) ([]*Group, error), error) {
+	entries, err := g.Op.Search(g.Base, "(member:"+InChainOID+":="+gldap.EscapeFilter(dn)+")")
+	if err != nil {
+		return nil, err
+	}
+	var all []*Group
+	for _, entry := range entries {
+		if group, ok := g.newGroup(entry); ok {
+			all = append(all, group)
+		}
+	}
+	return func(member string) ([]*Group, error) {
+		var groups []*Group
+		for _, group := range all {
+			if group.holds("", member) {
+				groups = append(groups, group)
+			}
+		}
+		return groups, nil
+	}, nil
+}
+
+// walkParents searches the groups having member as member or uniqueMember,
+// and as memberUid the groups holding uid when it is given.
+func (g *Groups) walkParents(member, uid string) ([]*Group, error) {
+	escaped := gldap.EscapeFilter(member)
+	groups, err := g.containing("(|(member="+escaped+")(uniqueMember="+escaped+"))", "", member)
+	if err != nil {
+		return nil, err
+	}
+	if uid != "" {
+		posix, err := g.containing("(memberUid="+gldap.EscapeFilter(uid)+")", MemberUIDAttribute, uid)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range posix {
+			if !slices.ContainsFunc(groups, func(item *Group) bool { return SameDN(item.DN, group.DN) }) {
+				groups = append(groups, group)
+			}
+		}
+	}
+	return groups, nil
+}
+
+// containing searches the groups matching filter and keeps those that really hold value.
+func (g *Groups) containing(filter, attribute, value string) ([]*Group, error) {
+	entries, err := g.Op.Search(g.Base, "(&"+groupFilter+filter+")")
+	if err != nil {
+		return nil, err
+	}
+	var groups []*Group
+	for _, entry := range entries {
+		if group, ok := g.newGroup(entry); ok && group.holds(attribute, value) {
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
+
+// holds reports whether value is in attribute, in any DN valued member attribute when attribute is empty.
+func (group *Group) holds(attribute, value string) bool {
+	for _, attr := range group.MemberAttributes {
+		if (attribute == "" && attr != MemberUIDAttribute) || attr == attribute {
+			if _, exist := containsValue(attr, group.values[attr], value); exist {
+				return true
+			}
+		}
+	}
+	return false
+}
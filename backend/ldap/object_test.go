@@ -161,13 +161,19 @@ func TestObjectParse(t *testing.T){
 	}
 
 	for _, value := range values{
-		chain := parser.GetInheritenceChain(value.name)
+		chain, err := parser.GetInheritenceChain(value.name)
+		if err != nil {
+			t.Fatal(err)
+		}
 		t.Logf("chain %v", chain)
 		if !slices.Equal(chain, value.chain){
 			t.Errorf("get value: %s, expect: %s", chain, value.chain)
 		}
 
-		musts, mays := parser.GetAllAttributees(value.name)
+		musts, mays, err := parser.GetAllAttributees(value.name)
+		if err != nil {
+			t.Fatal(err)
+		}
 		t.Logf("musts: %v, may: %s: ", musts, mays)
 		if !slices.Equal(musts, value.must) {
 			t.Errorf("get must: %v, expect %v:", musts, value.must)
@@ -182,3 +188,167 @@ func TestObjectParse(t *testing.T){
 
 
 
+
+func TestObjectClassGrammar(t *testing.T) {
+	values := []struct {
+		source string
+		input  string
+		expect ObjectClass
+	}{
+		{
+			source: "OpenLDAP cosine.schema",
+			input:  "( 0.9.2342.19200300.100.4.20 NAME 'pilotOrganization' SUP ( organization $ organizationalUnit ) STRUCTURAL MAY buildingName )",
+			expect: ObjectClass{Oid: "0.9.2342.19200300.100.4.20", Name: []string{"pilotOrganization"}, Parent: "organization",
+				Superiors: []string{"organization", "organizationalUnit"}, Type: STRUCTURAL, May: []string{"buildingName"}},
+		},
+		{
+			source: "OpenLDAP nis.schema",
+			input:  "( 1.3.6.1.1.1.2.0 NAME 'posixAccount' DESC 'Abstraction of an account with POSIX attributes' SUP top AUXILIARY MUST ( cn $ uid $ uidNumber $ gidNumber $ homeDirectory ) MAY ( userPassword $ loginShell $ gecos $ description ) )",
+			expect: ObjectClass{Oid: "1.3.6.1.1.1.2.0", Name: []string{"posixAccount"}, Parent: "top", Superiors: []string{"top"},
+				Description: "Abstraction of an account with POSIX attributes", Type: AUXILIARY,
+				Must: []string{"cn", "uid", "uidNumber", "gidNumber", "homeDirectory"},
+				May:  []string{"userPassword", "loginShell", "gecos", "description"}},
+		},
+		{
+			source: "OpenLDAP cn=config",
+			input:  "( 1.3.6.1.4.1.4203.1.12.2.4.2.1 NAME 'olcMdbConfig' DESC 'MDB backend configuration' SUP olcDatabaseConfig STRUCTURAL MUST olcDbDirectory MAY ( olcDbCheckpoint $ olcDbEnvFlags $ olcDbNoSync $ olcDbIndex $ olcDbMaxReaders $ olcDbMaxSize $ olcDbMode $ olcDbSearchStack $ olcDbMaxEntrySize $ olcDbRtxnSize ) )",
+			expect: ObjectClass{Oid: "1.3.6.1.4.1.4203.1.12.2.4.2.1", Name: []string{"olcMdbConfig"}, Parent: "olcDatabaseConfig",
+				Superiors: []string{"olcDatabaseConfig"}, Description: "MDB backend configuration", Type: STRUCTURAL,
+				Must: []string{"olcDbDirectory"},
+				May:  []string{"olcDbCheckpoint", "olcDbEnvFlags", "olcDbNoSync", "olcDbIndex", "olcDbMaxReaders", "olcDbMaxSize", "olcDbMode", "olcDbSearchStack", "olcDbMaxEntrySize", "olcDbRtxnSize"}},
+		},
+		{
+			source: "389-DS",
+			input:  "( 2.16.840.1.113730.3.2.2 NAME 'inetOrgPerson' SUP organizationalPerson STRUCTURAL MAY ( audio $ businessCategory $ carLicense $ departmentNumber $ displayName ) X-ORIGIN ( 'RFC 2798' 'user defined' ) )",
+			expect: ObjectClass{Oid: "2.16.840.1.113730.3.2.2", Name: []string{"inetOrgPerson"}, Parent: "organizationalPerson",
+				Superiors: []string{"organizationalPerson"}, Type: STRUCTURAL,
+				May:        []string{"audio", "businessCategory", "carLicense", "departmentNumber", "displayName"},
+				Extensions: map[string][]string{"X-ORIGIN": {"RFC 2798", "user defined"}}},
+		},
+		{
+			source: "389-DS non numeric oid",
+			input:  "( nsAdminGroup-oid NAME 'nsAdminGroup' DESC 'Netscape defined objectclass' SUP top STRUCTURAL MUST cn MAY ( nsAdminGroupName $ description $ nsConfigRoot $ nsAdminSIEDN ) X-ORIGIN 'Netscape Administration Services' )",
+			expect: ObjectClass{Oid: "nsAdminGroup-oid", Name: []string{"nsAdminGroup"}, Parent: "top", Superiors: []string{"top"},
+				Description: "Netscape defined objectclass", Type: STRUCTURAL, Must: []string{"cn"},
+				May:        []string{"nsAdminGroupName", "description", "nsConfigRoot", "nsAdminSIEDN"},
+				Extensions: map[string][]string{"X-ORIGIN": {"Netscape Administration Services"}}},
+		},
+		{
+			source: "Active Directory",
+			input:  "( 2.5.6.0 NAME 'top' ABSTRACT MUST (instanceType $ nTSecurityDescriptor $ objectCategory $ objectClass ) MAY (cn $ description $ distinguishedName $ whenCreated ) )",
+			expect: ObjectClass{Oid: "2.5.6.0", Name: []string{"top"}, Type: ABSTRACT,
+				Must: []string{"instanceType", "nTSecurityDescriptor", "objectCategory", "objectClass"},
+				May:  []string{"cn", "description", "distinguishedName", "whenCreated"}},
+		},
+		{
+			source: "Active Directory",
+			input:  "( 1.2.840.113556.1.5.9 NAME 'user' SUP organizationalPerson STRUCTURAL MAY (userAccountControl $ sAMAccountName $ memberOf ) )",
+			expect: ObjectClass{Oid: "1.2.840.113556.1.5.9", Name: []string{"user"}, Parent: "organizationalPerson",
+				Superiors: []string{"organizationalPerson"}, Type: STRUCTURAL,
+				May: []string{"userAccountControl", "sAMAccountName", "memberOf"}},
+		},
+		{
+			source: "kind word inside the description",
+			input:  "( 1.2.3.5 NAME 'helper' DESC 'mix in for STRUCTURAL classes' AUXILIARY MAY cn )",
+			expect: ObjectClass{Oid: "1.2.3.5", Name: []string{"helper"}, Description: "mix in for STRUCTURAL classes",
+				Type: AUXILIARY, May: []string{"cn"}},
+		},
+		{
+			source: "obsolete with escapes and default kind",
+			input:  `( 1.2.3.4 NAME ( 'oldThing' 'legacyThing' ) DESC 'it\27s gone \5C deprecated' OBSOLETE SUP top MUST cn )`,
+			expect: ObjectClass{Oid: "1.2.3.4", Name: []string{"oldThing", "legacyThing"}, Parent: "top", Superiors: []string{"top"},
+				Description: `it's gone \ deprecated`, Obsolete: true, Type: STRUCTURAL, Must: []string{"cn"}},
+		},
+	}
+
+	for _, value := range values {
+		parser := NewObjectClassParser()
+		obj, err := parser.ParseObjectClass(value.input)
+		if err != nil {
+			t.Errorf("%s: %v", value.source, err)
+			continue
+		}
+		expect := value.expect
+		if obj.Oid != expect.Oid || obj.Parent != expect.Parent || obj.Description != expect.Description ||
+			obj.Type != expect.Type || obj.Obsolete != expect.Obsolete {
+			t.Errorf("%s: get %+v, expect %+v", value.source, *obj, expect)
+		}
+		for _, pair := range [][2][]string{{obj.Name, expect.Name}, {obj.Superiors, expect.Superiors}, {obj.Must, expect.Must}, {obj.May, expect.May}} {
+			if !slices.Equal(pair[0], pair[1]) {
+				t.Errorf("%s: get value: %v, expect: %v", value.source, pair[0], pair[1])
+			}
+		}
+		if len(obj.Extensions) != len(expect.Extensions) {
+			t.Errorf("%s: get extensions: %v, expect: %v", value.source, obj.Extensions, expect.Extensions)
+		}
+		for key, values := range expect.Extensions {
+			if !slices.Equal(obj.Extensions[key], values) {
+				t.Errorf("%s: get %s: %v, expect: %v", value.source, key, obj.Extensions[key], values)
+			}
+		}
+		for _, name := range expect.Name {
+			if parser.Objects[name] != obj {
+				t.Errorf("%s: %s is not registered", value.source, name)
+			}
+		}
+	}
+
+	invalids := []string{
+		"2.5.6.0 NAME 'top' ABSTRACT",
+		"( 2.5.6.0 NAME 'top' ABSTRACT",
+		"( 2.5.6.0 NAME 'top ABSTRACT )",
+		"( 2.5.6.0 NAME 'top' ABSTRACT STRUCTURAL )",
+		"( 2.5.6.0 NAME 'top' MUST ( ) )",
+		"( 2.5.6.0 NAME 'top' DESC 'bad \\zz escape' )",
+		"( 2.5.6.0 NAME 'top' FOO bar )",
+	}
+	for _, input := range invalids {
+		if _, err := NewObjectClassParser().ParseObjectClass(input); err == nil {
+			t.Errorf("expect error for %s", input)
+		}
+	}
+}
+
+func TestInheritanceDAG(t *testing.T) {
+	parser := NewObjectClassParser()
+	err := parser.ParseObjects([]string{
+		"( 2.5.6.0 NAME 'top' ABSTRACT MUST objectClass )",
+		"( 2.5.6.4 NAME 'organization' SUP top STRUCTURAL MUST o MAY description )",
+		"( 2.5.6.5 NAME 'organizationalUnit' SUP top STRUCTURAL MUST ou MAY ( description $ l ) )",
+		"( 0.9.2342.19200300.100.4.20 NAME 'pilotOrganization' SUP ( organization $ organizationalUnit ) STRUCTURAL MAY buildingName )",
+		"( 1.1.1 NAME 'orphan' SUP missing STRUCTURAL )",
+		"( 1.1.2 NAME 'loopA' SUP loopB STRUCTURAL )",
+		"( 1.1.3 NAME 'loopB' SUP loopA STRUCTURAL )",
+		"( 1.1.4 NAME 'self' SUP self STRUCTURAL )",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain, err := parser.GetInheritenceChain("pilotOrganization")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"pilotOrganization", "organization", "organizationalUnit", "top"}; !slices.Equal(chain, expect) {
+		t.Errorf("get chain %v, expect %v", chain, expect)
+	}
+	musts, mays, err := parser.GetAllAttributees("PILOTORGANIZATION")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"o", "ou", "objectClass"}; !slices.Equal(musts, expect) {
+		t.Errorf("get must %v, expect %v", musts, expect)
+	}
+	if expect := []string{"buildingName", "description", "l"}; !slices.Equal(mays, expect) {
+		t.Errorf("get may %v, expect %v", mays, expect)
+	}
+
+	for _, name := range []string{"orphan", "loopA", "loopB", "self", "unknown"} {
+		if chain, err := parser.GetInheritenceChain(name); err == nil {
+			t.Errorf("%s: expect an error, get chain %v", name, chain)
+		}
+		if _, _, err := parser.GetAllAttributees(name); err == nil {
+			t.Errorf("%s: expect an error", name)
+		}
+	}
+}
@@ -1,19 +1,22 @@
 package ldap
 
 import (
-	"errors"
-	"regexp"
+	"fmt"
+	"slices"
 	"strings"
 )
 
 type ObjectClass struct {
 	Oid string   	`json:"oid"`
 	Name []string	`json:"name"`
-	Parent string	`json:"parent"`
+	Parent string	`json:"parent"`	// first superior, kept for older clients
+	Superiors []string	`json:"superiors"`
 	Description string	`json:"description"`
+	Obsolete bool	`json:"obsolete"`
 	Type string		`json:"type"`
 	Must []string	`json:"must"`
 	May []string	`json:"may"`
+	Extensions map[string][]string	`json:"extensions,omitempty"`	// X- keywords, e.g. X-ORIGIN
 }
 
 const (
@@ -22,130 +25,87 @@ const (
 	AUXILIARY = "AUXILIARY"
 )
 
+// ObjectClassParser holds the parsed subschema: object classes, attribute
+// types, matching rules and syntaxes. It is filled by the Parse methods and
+// must not change once it is shared, see LDAPOperation.Schema.
 type ObjectClassParser struct {
 	Objects map[string]*ObjectClass   `json:"objects"`
+	AttributeTypes map[string]*AttributeType	`json:"attributeTypes"`
+	MatchingRules map[string]*MatchingRule	`json:"matchingRules"`
+	Syntaxes map[string]*LDAPSyntax	`json:"ldapSyntaxes"`
+	attributeIndex map[string]*AttributeType	// lower case names and oids
 }
 
+// emptySchema is returned before a schema is loaded.
+var emptySchema = NewObjectClassParser()
+
 func NewObjectClassParser() *ObjectClassParser {
 	return &ObjectClassParser{
 		Objects: make(map[string]*ObjectClass),
+		AttributeTypes: make(map[string]*AttributeType),
+		MatchingRules: make(map[string]*MatchingRule),
+		Syntaxes: make(map[string]*LDAPSyntax),
+		attributeIndex: make(map[string]*AttributeType),
 	}
 }
 
+var objectClassKeywords = map[string]int{
+	"NAME":       argQDescrs,
+	"DESC":       argQDString,
+	"OBSOLETE":   argNone,
+	"SUP":        argOIDs,
+	ABSTRACT:     argNone,
+	STRUCTURAL:   argNone,
+	AUXILIARY:    argNone,
+	"MUST":       argOIDs,
+	"MAY":        argOIDs,
+}
+
+// ParseObjectClass reads an ObjectClassDescription (RFC 4512 4.1.1), e.g.
 // ( 2.5.6.0 NAME 'top' DESC 'top of the superclass chain' ABSTRACT MUST objectClass )
-// ( 2.5.6.4 NAME 'organization' DESC 'RFC2256: an organization' SUP top STRUCTURAL MUST o MAY ( userPassword $ searchGuide $ seeAlso $ businessCategory $ x121Address $ registeredAddress $ destinationIndicator $ preferredDeliveryMethod $ telexNumber $ teletexTerminalIdentifier $ telephoneNumber $  internationaliSDNNumber $ facsimileTelephoneNumber $ street $ postOfficeBox $ postalCode $ postalAddress $ physicalDeliveryOfficeName $ st $ l $ description ) )
+// ( 2.5.6.4 NAME 'organization' DESC 'RFC2256: an organization' SUP top STRUCTURAL MUST o MAY ( userPassword $ searchGuide $ seeAlso ) )
 func (p *ObjectClassParser) ParseObjectClass(presention string) (*ObjectClass, error) {
-	obj := &ObjectClass{}
-	presention = strings.Trim(presention," (")
-
-	// oid
-	oidPattern := regexp.MustCompile(`^[\d.]+`)
-	oid := oidPattern.FindString(presention)
-	obj.Oid = oid
-
-	// name
-	namePattern := regexp.MustCompile(`NAME\s+'([-\w]+)'|NAME\s+\(\s*([^)]+)\s*\)`)
-	matches := namePattern.FindStringSubmatch(presention)
-	if len(matches) == 0 {
-		return nil,errors.New("invalid name")
-	}
-	if matches[1] != ""{
-		obj.Name = append(obj.Name, matches[1])
-	}else if matches[2] != ""{
-		obj.Name = p.ParseObjectNames(matches[2])
-	}
-	// desc
-	descPattern := regexp.MustCompile(`DESC\s+'([^']+)'`)
-	matches = descPattern.FindStringSubmatch(presention)
-	if len(matches)==2 {
-		obj.Description = ""
-	}else if len(matches) == 2 {
-		obj.Description = matches[1]
-	}
-	
-
-	// parent
-	if obj.Name[0] == "top"{
-		obj.Parent = ""
-	}else{
-		supPattern := regexp.MustCompile(`SUP\s+(\w+)`)
-		matches = supPattern.FindStringSubmatch(presention)
-		if len(matches) == 0{
-			obj.Parent = ""
-		}else if len(matches) == 2 {
-			obj.Parent = matches[1]
-		}
+	desc, err := parseSchemaDescription(presention, objectClassKeywords)
+	if err != nil {
+		return nil, fmt.Errorf("invalid objectClass %q: %w", presention, err)
 	}
-	// type
-	if strings.Contains(presention, STRUCTURAL) {
-		obj.Type = STRUCTURAL
-	}else if strings.Contains(presention, AUXILIARY) {
-		obj.Type = AUXILIARY		
-	}else if strings.Contains(presention, ABSTRACT) {
-		obj.Type = ABSTRACT
-	}else {
-		return nil,errors.New("invalid TYPE")
-	}
-
-	// must
-	mustPattern := regexp.MustCompile(`MUST\s+(\w+)|MUST\s+\(\s*([^)]+)\s*\)`)
-	matches = mustPattern.FindStringSubmatch(presention)
-	if len(matches) > 0 {
-		if matches[1] != "" {
-			var tmp string = matches[1]
-			obj.Must = append(obj.Must, tmp)
-		}else if matches[2] != ""{
-			res, err := p.ParseAttributeList(matches[2])
-			if err != nil {
-				return nil,err
-			}
-			obj.Must = append(obj.Must,res...)
-		}
+	obj := &ObjectClass{
+		Oid: desc.OID,
+		Name: desc.Values["NAME"],
+		Description: desc.first("DESC"),
+		Obsolete: desc.has("OBSOLETE"),
+		Superiors: desc.Values["SUP"],
+		Must: desc.Values["MUST"],
+		May: desc.Values["MAY"],
 	}
-
-	// may
-	mayPattern := regexp.MustCompile(`MAY\s+(\w+)|MAY\s+\(\s*([^)]+)\s*\)`)
-	matches = mayPattern.FindStringSubmatch(presention)
-	
-	if len(matches) > 0 {
-		if matches[1] != "" {
-			var tmp string = matches[1]
-			obj.May = append(obj.May, tmp)
-		}else if matches[2] != ""{
-			res, err := p.ParseAttributeList(matches[2])
-			if err != nil {
-				return nil,err
-			}
-			obj.May = append(obj.May,res...)
-		}
+	if len(desc.Extensions) > 0 {
+		obj.Extensions = desc.Extensions
 	}
-	for _, nm := range obj.Name {
-		p.Objects[nm] = obj
+	if len(obj.Superiors) > 0 {
+		obj.Parent = obj.Superiors[0]
 	}
-	return  obj,nil
-}
 
-func (p *ObjectClassParser) ParseObjectNames(names string) []string {
-	var nms []string
-	items := strings.Split(names, " ")
-	for _, item := range items {
-		item = strings.Trim(item," '")
-		if item != "" {
-			nms = append(nms, item)
+	// the kind defaults to STRUCTURAL when absent
+	obj.Type = STRUCTURAL
+	kinds := 0
+	for _, kind := range []string{ABSTRACT, STRUCTURAL, AUXILIARY} {
+		if desc.has(kind) {
+			obj.Type = kind
+			kinds++
 		}
 	}
-	return nms
-}
-
-func (p *ObjectClassParser) ParseAttributeList(attrs string, ) ([]string, error){
-	fields := strings.Split(attrs, "$")
-	var results []string
+	if kinds > 1 {
+		return nil, fmt.Errorf("invalid objectClass %s: more than one kind given", obj.Oid)
+	}
+	// an object class without a name is only reachable by its oid
+	if len(obj.Name) == 0 {
+		obj.Name = []string{obj.Oid}
+	}
 
-	for _, field := range fields {
-		field = strings.TrimSpace(field)
-		results = append(results, field)
+	for _, nm := range obj.Name {
+		p.Objects[nm] = obj
 	}
-	return results,nil
+	return  obj,nil
 }
 
 func (p *ObjectClassParser) ParseObjects(definition []string) error {
@@ -160,31 +120,81 @@ func (p *ObjectClassParser) ParseObjects(definition []string) error {
 	return nil
 }
 
-func (p *ObjectClassParser) GetInheritenceChain(obj string) []string {
-	var result [] string
-
-	for obj != "" {
-		if objclass, exist := p.Objects[obj]; exist {
-			result = append(result, objclass.Name...)
-			obj = objclass.Parent
-		}
+// GetInheritenceChain returns the names of obj and of all its superiors, a
+// class always comes before its superiors and top comes last. Superiors form
+// a DAG: a class may have several, e.g. SUP ( organization $ organizationalUnit ).
+func (p *ObjectClassParser) GetInheritenceChain(obj string) ([]string, error) {
+	classes, err := p.ancestry(obj)
+	if err != nil {
+		return nil, err
 	}
-	
-	return p.RemoveDuplicates(result)
+	var result []string
+	for _, objclass := range classes {
+		result = append(result, objclass.Name...)
+	}
+	return p.RemoveDuplicates(result), nil
 }
 
 
-func (p *ObjectClassParser) GetAllAttributees(objclass string) ([]string, []string){
+func (p *ObjectClassParser) GetAllAttributees(objclass string) ([]string, []string, error){
 	var must,may []string
 
-	chains := p.GetInheritenceChain(objclass)
+	classes, err := p.ancestry(objclass)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	for _, item := range chains {
-		obj := p.Objects[item]
+	for _, obj := range classes {
 		must = append(must, obj.Must...)
 		may = append(may, obj.May...)
 	}
-	return p.RemoveDuplicates(must), p.RemoveDuplicates(may)
+	return p.RemoveDuplicates(must), p.RemoveDuplicates(may), nil
+}
+
+// ancestry orders obj and its superiors topologically, subclasses first. An
+// unknown superior or a class inheriting from itself is an error.
+func (p *ObjectClassParser) ancestry(name string) ([]*ObjectClass, error) {
+	start, exist := p.objectClass(name)
+	if !exist {
+		return nil, fmt.Errorf("unknown objectClass %s", name)
+	}
+	const (
+		visiting = 1
+		done = 2
+	)
+	state := make(map[*ObjectClass]int)
+	var path []string
+	var order []*ObjectClass
+	var visit func(obj *ObjectClass) error
+	visit = func(obj *ObjectClass) error {
+		switch state[obj] {
+		case visiting:
+			return fmt.Errorf("objectClass %s inherits from itself: %s -> %s", obj.Name[0], strings.Join(path, " -> "), obj.Name[0])
+		case done:
+			return nil
+		}
+		state[obj] = visiting
+		path = append(path, obj.Name[0])
+		// walk backwards so the first superior ends up first after reversing
+		for i := len(obj.Superiors) - 1; i >= 0; i-- {
+			parent, exist := p.objectClass(obj.Superiors[i])
+			if !exist {
+				return fmt.Errorf("superior %s of objectClass %s is unknown", obj.Superiors[i], obj.Name[0])
+			}
+			if err := visit(parent); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[obj] = done
+		order = append(order, obj)
+		return nil
+	}
+	if err := visit(start); err != nil {
+		return nil, err
+	}
+	slices.Reverse(order)
+	return order, nil
 }
 
 
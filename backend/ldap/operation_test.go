@@ -2,6 +2,7 @@ package ldap
 
 import (
 	"os"
+	"slices"
 	"testing"
 )
 
@@ -59,3 +60,106 @@ func TestAttributeOperation(t *testing.T){
 	op.GetObjectClassAttributes()
 }
 
+
+func TestValidateChanges(t *testing.T) {
+	valid := []AttributeChange{
+		{Operation: "replace", Attribute: "mail", Values: []string{"alice@example.com"}},
+		{Operation: "add", Attribute: "member", Values: []string{"uid=bob,ou=person,dc=example,dc=com"}},
+		{Operation: "delete", Attribute: "member", Values: []string{"uid=carl,ou=person,dc=example,dc=com"}},
+		{Operation: "DELETE", Attribute: "description"},
+	}
+	if err := ValidateChanges(valid); err != nil {
+		t.Fatal(err)
+	}
+
+	invalids := [][]AttributeChange{
+		nil,
+		{{Operation: "add", Attribute: "mail"}},
+		{{Operation: "increment", Attribute: "uidNumber", Values: []string{"1"}}},
+		{{Operation: "replace", Values: []string{"x"}}},
+	}
+	for _, changes := range invalids {
+		if err := ValidateChanges(changes); err == nil {
+			t.Errorf("expect error for changes %v", changes)
+		}
+	}
+}
+
+func TestSortChildrenFirst(t *testing.T) {
+	dns := SortChildrenFirst([]string{
+		"ou=people,dc=example,dc=com",
+		"uid=bob,ou=people,dc=example,dc=com",
+		"ou=team,ou=people,dc=example,dc=com",
+		"uid=alice,ou=team,ou=people,dc=example,dc=com",
+	})
+	expect := []string{
+		"uid=alice,ou=team,ou=people,dc=example,dc=com",
+		"ou=team,ou=people,dc=example,dc=com",
+		"uid=bob,ou=people,dc=example,dc=com",
+		"ou=people,dc=example,dc=com",
+	}
+	if !slices.Equal(dns, expect) {
+		t.Errorf("get value: %v, expect: %v", dns, expect)
+	}
+}
+
+func TestParseSearchOptions(t *testing.T) {
+	scopes := map[string]int{"base": 0, "one": 1, "sub": 2, "": 2}
+	for input, expect := range scopes {
+		scope, err := ParseScope(input)
+		if err != nil || scope != expect {
+			t.Errorf("scope %q: get %d %v, expect %d", input, scope, err, expect)
+		}
+	}
+	if _, err := ParseScope("children"); err == nil {
+		t.Error("expect error for unknown scope")
+	}
+
+	derefs := map[string]int{"": 0, "never": 0, "search": 1, "find": 2, "always": 3}
+	for input, expect := range derefs {
+		deref, err := ParseDeref(input)
+		if err != nil || deref != expect {
+			t.Errorf("deref %q: get %d %v, expect %d", input, deref, err, expect)
+		}
+	}
+	if _, err := ParseDeref("sometimes"); err == nil {
+		t.Error("expect error for unknown deref policy")
+	}
+}
+
+func TestDecodeRecord(t *testing.T) {
+	dn, attrs, err := decodeRecord(map[string][]string{
+		"DN":          {"cn=admins,ou=group,dc=example,dc=com"},
+		"objectClass": {"top", "groupOfNames"},
+		"member":      {"uid=alice,ou=person,dc=example,dc=com", "uid=bob,ou=person,dc=example,dc=com"},
+		"description": {"admins, operators"},
+		"jpegPhoto::": {"/9j/4AA="},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dn != "cn=admins,ou=group,dc=example,dc=com" {
+		t.Errorf("get dn %s", dn)
+	}
+	if len(attrs["member"]) != 2 || attrs["description"][0] != "admins, operators" {
+		t.Errorf("values must not be split, get %v", attrs)
+	}
+	if photo := attrs["jpegPhoto"]; len(photo) != 1 || photo[0] != "\xff\xd8\xff\xe0\x00" {
+		t.Errorf("expect decoded binary value, get %q", photo)
+	}
+
+	invalid := []map[string][]string{
+		{"cn": {"a"}},
+		{"DN": {"cn=a", "cn=b"}},
+		{"DN": {"cn=a"}, "jpegPhoto::": {"not base64!"}},
+	}
+	for _, record := range invalid {
+		if _, _, err := decodeRecord(record); err == nil {
+			t.Errorf("expect error for %v", record)
+		}
+	}
+
+	if values := SplitLegacyValue("top, person ,inetOrgPerson"); !slices.Equal(values, []string{"top", "person", "inetOrgPerson"}) {
+		t.Errorf("get legacy values %v", values)
+	}
+}
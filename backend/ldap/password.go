@@ -0,0 +1,260 @@
+package ldap
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+// PasswordModifyOID is the RFC 3062 Password Modify extended operation.
+const PasswordModifyOID = "1.3.6.1.4.1.4203.1.11.1"
+
+// hash schemes of userPassword used when the server lacks Password Modify
+const (
+	HashSSHA        = "SSHA"
+	HashSSHA512     = "SSHA512"
+	HashCryptSHA256 = "CRYPT-SHA256" // {CRYPT}$5$
+	HashCryptSHA512 = "CRYPT-SHA512" // {CRYPT}$6$
+)
+
+// DefaultPasswordLength is the length of generated passwords.
+const DefaultPasswordLength = 16
+
+// ParseHashScheme normalizes a user supplied hash scheme, empty means SSHA.
+func ParseHashScheme(scheme string) (string, error) {
+	switch strings.ToUpper(strings.TrimSpace(scheme)) {
+	case "", HashSSHA:
+		return HashSSHA, nil
+	case HashSSHA512, "SSHA-512":
+		return HashSSHA512, nil
+	case HashCryptSHA256, "SHA256-CRYPT", "$5$":
+		return HashCryptSHA256, nil
+	case HashCryptSHA512, "SHA512-CRYPT", "CRYPT", "$6$":
+		return HashCryptSHA512, nil
+	}
+	return "", fmt.Errorf("unknown password hash %q, expect one of SSHA, SSHA512, CRYPT-SHA256, CRYPT-SHA512", scheme)
+}
+
+// ChangePassword sets the password of dn. The Password Modify extended
+// operation is used when the server supports it, otherwise userPassword is
+// replaced with a value hashed by Profile.PasswordHash. oldPassword may be
+// empty for an administrator, an empty newPassword asks for a generated one.
+// The new password is returned. A change the password policy refuses, e.g.
+// a too short password, fails with a *PolicyError.
+func (op *LDAPOperation) ChangePassword(dn, oldPassword, newPassword string) (string, error) {
+	if op.Conn == nil {
+		return "", errors.New("LDAP connection is not established")
+	}
+	if dn == "" {
+		return "", errors.New("please give an valid dn")
+	}
+	dse, err := op.RootDSE()
+	if err != nil {
+		return "", err
+	}
+
+	if newPassword == "" {
+		if newPassword, err = GeneratePassword(DefaultPasswordLength); err != nil {
+			return "", err
+		}
+	}
+	if dse.SupportsExtension(PasswordModifyOID) {
+		err = passwordModify(op.Conn, dn, oldPassword, newPassword)
+	} else {
+		err = op.replacePassword(dn, oldPassword, newPassword)
+	}
+	if err != nil {
+		return "", err
+	}
+	if SameDN(dn, op.User) {
+		op.Pwd = newPassword
+	}
+	return newPassword, nil
+}
+
+// replacePassword writes a locally hashed userPassword, the old password is
+// checked by binding with it on a separate connection.
+func (op *LDAPOperation) replacePassword(dn, oldPassword, newPassword string) error {
+	if oldPassword != "" {
+		conn, err := op.dial()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if _, err := bindWithPolicy(conn, dn, oldPassword); err != nil {
+			return fmt.Errorf("old password is not correct: %w", err)
+		}
+	}
+	hashed, err := HashPassword(op.Profile.PasswordHash, newPassword)
+	if err != nil {
+		return err
+	}
+	modifyReq := gldap.NewModifyRequest(dn, policyControls())
+	modifyReq.Replace("userPassword", []string{hashed})
+	_, err = op.Conn.ModifyWithResult(modifyReq)
+	return withPolicy(err, nil)
+}
+
+// HashPassword hashes password for userPassword, e.g. {SSHA}base64(sha1(password+salt)+salt).
+func HashPassword(scheme, password string) (string, error) {
+	scheme, err := ParseHashScheme(scheme)
+	if err != nil {
+		return "", err
+	}
+	switch scheme {
+	case HashSSHA:
+		return saltedHash("{SSHA}", sha1.New(), password)
+	case HashSSHA512:
+		return saltedHash("{SSHA512}", sha512.New(), password)
+	}
+
+	salt, err := randomString(cryptAlphabet, 16)
+	if err != nil {
+		return "", err
+	}
+	if scheme == HashCryptSHA256 {
+		return "{CRYPT}" + shaCrypt(sha256.New, "$5$", sha256Order, password, salt, shaCryptRounds), nil
+	}
+	return "{CRYPT}" + shaCrypt(sha512.New, "$6$", sha512Order, password, salt, shaCryptRounds), nil
+}
+
+func saltedHash(prefix string, h hash.Hash, password string) (string, error) {
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	h.Write([]byte(password))
+	h.Write(salt)
+	return prefix + base64.StdEncoding.EncodeToString(append(h.Sum(nil), salt...)), nil
+}
+
+const passwordAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789!#%+-=?@_"
+
+// GeneratePassword returns a random password, ambiguous characters such as
+// l, 1, O and 0 are left out.
+func GeneratePassword(length int) (string, error) {
+	if length < 8 {
+		return "", errors.New("a password needs at least 8 characters")
+	}
+	return randomString(passwordAlphabet, length)
+}
+
+func randomString(alphabet string, length int) (string, error) {
+	result := make([]byte, length)
+	max := big.NewInt(int64(len(alphabet)))
+	for i := range result {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		result[i] = alphabet[n.Int64()]
+	}
+	return string(result), nil
+}
+
+// SHA-crypt as specified by Ulrich Drepper, the format of crypt(3) for $5$ and $6$.
+
+const (
+	cryptAlphabet  = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	shaCryptRounds = 5000 // default of the specification, omitted from the output
+)
+
+// byte order of the final encoding, three bytes give four characters
+var sha256Order = [][]int{
+	{0, 10, 20}, {21, 1, 11}, {12, 22, 2}, {3, 13, 23}, {24, 4, 14},
+	{15, 25, 5}, {6, 16, 26}, {27, 7, 17}, {18, 28, 8}, {9, 19, 29}, {31, 30},
+}
+
+var sha512Order = [][]int{
+	{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4},
+	{47, 5, 26}, {6, 27, 48}, {28, 49, 7}, {50, 8, 29}, {9, 30, 51},
+	{31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13}, {56, 14, 35},
+	{15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19},
+	{62, 20, 41}, {63},
+}
+
+func shaCrypt(newHash func() hash.Hash, prefix string, order [][]int, password, salt string, rounds int) string {
+	if len(salt) > 16 {
+		salt = salt[:16]
+	}
+	pw, s := []byte(password), []byte(salt)
+	sum := func(parts ...[]byte) []byte {
+		h := newHash()
+		for _, part := range parts {
+			h.Write(part)
+		}
+		return h.Sum(nil)
+	}
+	// repeat returns length bytes of block repeated
+	repeat := func(block []byte, length int) []byte {
+		return bytes.Repeat(block, length/len(block)+1)[:length]
+	}
+
+	alternate := sum(pw, s, pw)
+	h := newHash()
+	h.Write(pw)
+	h.Write(s)
+	h.Write(repeat(alternate, len(pw)))
+	for n := len(pw); n > 0; n >>= 1 {
+		if n&1 != 0 {
+			h.Write(alternate)
+		} else {
+			h.Write(pw)
+		}
+	}
+	digest := h.Sum(nil)
+
+	pBytes := repeat(sum(bytes.Repeat(pw, len(pw))), len(pw))
+	sBytes := repeat(sum(bytes.Repeat(s, 16+int(digest[0]))), len(s))
+
+	for i := 0; i < rounds; i++ {
+		h := newHash()
+		if i%2 != 0 {
+			h.Write(pBytes)
+		} else {
+			h.Write(digest)
+		}
+		if i%3 != 0 {
+			h.Write(sBytes)
+		}
+		if i%7 != 0 {
+			h.Write(pBytes)
+		}
+		if i%2 != 0 {
+			h.Write(digest)
+		} else {
+			h.Write(pBytes)
+		}
+		digest = h.Sum(nil)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	if rounds != shaCryptRounds {
+		fmt.Fprintf(&sb, "rounds=%d$", rounds)
+	}
+	sb.WriteString(salt)
+	sb.WriteByte('$')
+	for _, group := range order {
+		// the first index is the most significant byte, a short group gives fewer characters
+		value, chars := 0, len(group)+1
+		for _, index := range group {
+			value = value<<8 | int(digest[index])
+		}
+		for j := 0; j < chars; j++ {
+			sb.WriteByte(cryptAlphabet[value&0x3f])
+			value >>= 6
+		}
+	}
+	return sb.String()
+}
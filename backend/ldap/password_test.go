@@ -0,0 +1,82 @@
+package ldap
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestShaCrypt(t *testing.T) {
+	// test vectors of the SHA-crypt specification
+	values := []struct {
+		sha512 bool
+		salt   string
+		rounds int
+		expect string
+	}{
+		{false, "saltstring", 5000, "$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5"},
+		{false, "saltstringsaltstring", 10000, "$5$rounds=10000$saltstringsaltst$3xv.VbSHBb41AL9AvLeujZkZRBAwqFMz2.opqey6IcA"},
+		{true, "saltstring", 5000, "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"},
+		{true, "saltstringsaltstring", 10000, "$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v."},
+	}
+	for _, value := range values {
+		var get string
+		if value.sha512 {
+			get = shaCrypt(sha512.New, "$6$", sha512Order, "Hello world!", value.salt, value.rounds)
+		} else {
+			get = shaCrypt(sha256.New, "$5$", sha256Order, "Hello world!", value.salt, value.rounds)
+		}
+		if get != value.expect {
+			t.Errorf("get %s, expect %s", get, value.expect)
+		}
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	hashed, err := HashPassword("", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(hashed, "{SSHA}"))
+	if err != nil || !strings.HasPrefix(hashed, "{SSHA}") || len(raw) != sha1.Size+8 {
+		t.Fatalf("unexpected SSHA value %s", hashed)
+	}
+	// the digest covers password and salt
+	digest := sha1.Sum(append([]byte("secret"), raw[sha1.Size:]...))
+	if string(digest[:]) != string(raw[:sha1.Size]) {
+		t.Errorf("SSHA digest does not match")
+	}
+
+	prefixes := map[string]string{"ssha512": "{SSHA512}", "CRYPT-SHA256": "{CRYPT}$5$", "crypt": "{CRYPT}$6$"}
+	for scheme, prefix := range prefixes {
+		hashed, err := HashPassword(scheme, "secret")
+		if err != nil || !strings.HasPrefix(hashed, prefix) {
+			t.Errorf("%s: get %s %v, expect prefix %s", scheme, hashed, err, prefix)
+		}
+	}
+	if again, _ := HashPassword("CRYPT-SHA512", "secret"); again == hashed {
+		t.Error("expect a new salt for every hash")
+	}
+	if _, err := HashPassword("MD5", "secret"); err == nil {
+		t.Error("expect error for unknown scheme")
+	}
+}
+
+func TestGeneratePassword(t *testing.T) {
+	password, err := GeneratePassword(DefaultPasswordLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(password) != DefaultPasswordLength || strings.ContainsAny(password, "l1O0") {
+		t.Errorf("unexpected password %s", password)
+	}
+	if other, _ := GeneratePassword(DefaultPasswordLength); other == password {
+		t.Error("expect random passwords")
+	}
+	if _, err := GeneratePassword(4); err == nil {
+		t.Error("expect error for a short password")
+	}
+}
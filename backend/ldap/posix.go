@@ -0,0 +1,317 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+const (
+	UIDNumberAttribute = "uidNumber"
+	GIDNumberAttribute = "gidNumber"
+)
+
+// poolRetries bounds the compare-and-modify attempts on a busy id pool.
+const poolRetries = 10
+
+// ErrIDRangeExhausted is returned when every number of the range is taken.
+var ErrIDRangeExhausted = errors.New("no free number left in the id range")
+
+// IDRange is an inclusive range of uidNumber or gidNumber values.
+type IDRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// ParseIDRange reads a range such as 10000-59999.
+func ParseIDRange(value string) (IDRange, error) {
+	first, last, found := strings.Cut(strings.TrimSpace(value), "-")
+	if !found {
+		return IDRange{}, fmt.Errorf("invalid id range %q, expect min-max", value)
+	}
+	low, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil {
+		return IDRange{}, fmt.Errorf("invalid id range %q: %w", value, err)
+	}
+	high, err := strconv.Atoi(strings.TrimSpace(last))
+	if err != nil {
+		return IDRange{}, fmt.Errorf("invalid id range %q: %w", value, err)
+	}
+	if low < 0 || high < low {
+		return IDRange{}, fmt.Errorf("invalid id range %q, expect 0 <= min <= max", value)
+	}
+	return IDRange{Min: low, Max: high}, nil
+}
+
+func (r IDRange) String() string {
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+// PosixAccount asks for a new account, see Accounts.CreateUser.
+type PosixAccount struct {
+	Attributes map[string][]string `json:"attributes"` // uidNumber and gidNumber are allocated when missing
+	Parent     string              `json:"parent"`     // overrides the parent of the template
+	// DN or cn of an existing posixGroup, empty creates a group named after the user
+	PrimaryGroup string `json:"primaryGroup"`
+}
+
+// ProvisionedAccount is the created account, Group is set when a user
+// private group was created with it.
+type ProvisionedAccount struct {
+	DN         string              `json:"dn"`
+	Attributes map[string][]string `json:"attributes"`
+	GroupDN    string              `json:"groupDN"`
+	Group      *RenderedEntry      `json:"group,omitempty"`
+}
+
+// Accounts creates posixAccount entries with unique uid and gid numbers.
+type Accounts struct {
+	Op            LdapOperation
+	Base          string
+	UIDNumbers    IDRange
+	GIDNumbers    IDRange
+	IDPoolDN      string // sambaUnixIdPool entry holding the next numbers, empty searches the numbers in use
+	HomeDirectory string // {user} is replaced by the uid
+	LoginShell    string
+	GroupParent   string // where user private groups are created
+}
+
+// NewAccounts provisions accounts below the naming context of op as configured by its profile.
+func NewAccounts(op *LDAPOperation) (*Accounts, error) {
+	base, err := op.BaseDN()
+	if err != nil {
+		return nil, err
+	}
+	p := &op.Profile
+	return &Accounts{
+		Op:            op,
+		Base:          base,
+		UIDNumbers:    p.UIDNumbers,
+		GIDNumbers:    p.GIDNumbers,
+		IDPoolDN:      p.expand(p.IDPoolDN, base, ""),
+		HomeDirectory: p.HomeDirectory,
+		LoginShell:    p.LoginShell,
+		GroupParent:   p.expand(p.GroupParent, base, ""),
+	}, nil
+}
+
+// NextUIDNumber allocates an unused uidNumber.
+func (a *Accounts) NextUIDNumber() (int, error) {
+	return a.next(UIDNumberAttribute, a.UIDNumbers, "(uidNumber=*)")
+}
+
+// NextGIDNumber allocates a gidNumber no posixGroup uses.
+func (a *Accounts) NextGIDNumber() (int, error) {
+	return a.next(GIDNumberAttribute, a.GIDNumbers, "(&(objectClass=posixGroup)(gidNumber=*))")
+}
+
+// next allocates from the id pool when configured, otherwise it takes the
+// number after the highest one in use. Numbers below it are not reused, files
+// of a deleted account may still be owned by its number.
+func (a *Accounts) next(attribute string, idRange IDRange, filter string) (int, error) {
+	used, err := a.usedNumbers(attribute, idRange, filter)
+	if err != nil {
+		return 0, err
+	}
+	if a.IDPoolDN == "" {
+		next := idRange.Min
+		if len(used) > 0 {
+			next = slices.Max(used) + 1
+		}
+		if next > idRange.Max {
+			return 0, fmt.Errorf("%s %s: %w", attribute, idRange, ErrIDRangeExhausted)
+		}
+		return next, nil
+	}
+
+	for attempt := 0; attempt < poolRetries; attempt++ {
+		entries, err := a.Op.GetAttrOfObjectClass(a.IDPoolDN)
+		if err != nil {
+			return 0, fmt.Errorf("read id pool %s: %w", a.IDPoolDN, err)
+		}
+		current := entries[0].GetEqualFoldAttributeValue(attribute)
+		pooled, err := strconv.Atoi(current)
+		if err != nil {
+			return 0, fmt.Errorf("id pool %s has no valid %s: %q", a.IDPoolDN, attribute, current)
+		}
+		// numbers may have been given out without the pool, skip them
+		next := max(pooled, idRange.Min)
+		for slices.Contains(used, next) {
+			next++
+		}
+		if next > idRange.Max {
+			return 0, fmt.Errorf("%s %s: %w", attribute, idRange, ErrIDRangeExhausted)
+		}
+		// deleting the value read fails when another allocator changed it first
+		err = a.Op.ModifyRecord(a.IDPoolDN, []AttributeChange{
+			{Operation: ModifyDelete, Attribute: attribute, Values: []string{current}},
+			{Operation: ModifyAdd, Attribute: attribute, Values: []string{strconv.Itoa(next + 1)}},
+		})
+		if err == nil {
+			return next, nil
+		}
+		if !gldap.IsErrorAnyOf(err, gldap.LDAPResultNoSuchAttribute, gldap.LDAPResultAttributeOrValueExists) {
+			return 0, err
+		}
+	}
+	return 0, fmt.Errorf("id pool %s changed %d times while allocating %s, please retry", a.IDPoolDN, poolRetries, attribute)
+}
+
+// usedNumbers lists the values of attribute inside idRange.
+func (a *Accounts) usedNumbers(attribute string, idRange IDRange, filter string) ([]int, error) {
+	entries, err := a.Op.Search(a.Base, filter)
+	if err != nil {
+		return nil, err
+	}
+	var used []int
+	for _, entry := range entries {
+		// the pool holds the next free number, not a used one
+		if a.IDPoolDN != "" && SameDN(entry.DN, a.IDPoolDN) {
+			continue
+		}
+		for _, value := range entry.GetEqualFoldAttributeValues(attribute) {
+			if n, err := strconv.Atoi(value); err == nil && n >= idRange.Min && n <= idRange.Max {
+				used = append(used, n)
+			}
+		}
+	}
+	return used, nil
+}
+
+// CreateUser renders tmpl with the allocated numbers and adds the account.
+// Without a primary group a posixGroup named after the uid is created first,
+// it is removed again when the account can not be added. homeDirectory and
+// loginShell fall back to the configured values when the template has none.
+func (a *Accounts) CreateUser(tmpl *EntryTemplate, account PosixAccount) (*ProvisionedAccount, error) {
+	input := make(map[string][]string, len(account.Attributes)+2)
+	for name, values := range account.Attributes {
+		input[name] = values
+	}
+	uid := firstValue(input, "uid")
+	if uid == "" {
+		return nil, errors.New("uid is required for a posix account")
+	}
+
+	result := &ProvisionedAccount{}
+	gid := firstValue(input, GIDNumberAttribute)
+	switch {
+	case account.PrimaryGroup != "":
+		group, err := a.posixGroup(account.PrimaryGroup)
+		if err != nil {
+			return nil, err
+		}
+		result.GroupDN = group.DN
+		gid = group.GetAttributeValue(GIDNumberAttribute)
+	case gid == "":
+		number, err := a.NextGIDNumber()
+		if err != nil {
+			return nil, err
+		}
+		gid = strconv.Itoa(number)
+		result.Group = &RenderedEntry{
+			DN: JoinDN("cn="+gldap.EscapeDN(uid), a.GroupParent),
+			Attributes: map[string][]string{
+				"objectClass":      {"top", "posixGroup"},
+				"cn":               {uid},
+				GIDNumberAttribute: {gid},
+			},
+		}
+		result.GroupDN = result.Group.DN
+	}
+	setValue(input, GIDNumberAttribute, gid)
+	if firstValue(input, UIDNumberAttribute) == "" {
+		number, err := a.NextUIDNumber()
+		if err != nil {
+			return nil, err
+		}
+		setValue(input, UIDNumberAttribute, strconv.Itoa(number))
+	}
+
+	entry, err := tmpl.Render(input, account.Parent, a.Base)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(entry.Attributes["objectClass"], func(class string) bool { return strings.EqualFold(class, "posixAccount") }) {
+		entry.Attributes["objectClass"] = append(entry.Attributes["objectClass"], "posixAccount")
+	}
+	if firstValue(entry.Attributes, "homeDirectory") == "" && a.HomeDirectory != "" {
+		setValue(entry.Attributes, "homeDirectory", strings.ReplaceAll(a.HomeDirectory, "{user}", uid))
+	}
+	if firstValue(entry.Attributes, "loginShell") == "" && a.LoginShell != "" {
+		setValue(entry.Attributes, "loginShell", a.LoginShell)
+	}
+	if firstValue(entry.Attributes, "cn") == "" {
+		setValue(entry.Attributes, "cn", uid)
+	}
+
+	if result.Group != nil {
+		if err := a.Op.AddEntry(result.Group.DN, result.Group.Attributes); err != nil {
+			return nil, fmt.Errorf("create primary group %s: %w", result.Group.DN, err)
+		}
+	}
+	if err := a.Op.AddEntry(entry.DN, entry.Attributes); err != nil {
+		if result.Group != nil {
+			if cleanup := a.Op.DeleteRecord(result.Group.DN); cleanup != nil {
+				return nil, fmt.Errorf("%w, the primary group %s is left behind: %v", err, result.Group.DN, cleanup)
+			}
+		}
+		return nil, err
+	}
+	result.DN, result.Attributes = entry.DN, entry.Attributes
+	return result, nil
+}
+
+// posixGroup finds the posixGroup given by DN or cn.
+func (a *Accounts) posixGroup(group string) (*gldap.Entry, error) {
+	var entries []*gldap.Entry
+	var err error
+	if strings.Contains(group, "=") {
+		entries, err = a.Op.GetAttrOfObjectClass(group)
+	} else {
+		entries, err = a.Op.Search(a.Base, "(&(objectClass=posixGroup)(cn="+gldap.EscapeFilter(group)+"))")
+	}
+	if err != nil {
+		return nil, err
+	}
+	var found []*gldap.Entry
+	for _, entry := range entries {
+		isPosix := slices.ContainsFunc(entry.GetAttributeValues("objectClass"), func(class string) bool { return strings.EqualFold(class, "posixGroup") })
+		if isPosix && (strings.Contains(group, "=") || strings.EqualFold(entry.GetAttributeValue("cn"), group)) {
+			found = append(found, entry)
+		}
+	}
+	switch {
+	case len(found) == 0:
+		return nil, fmt.Errorf("no posixGroup %s found", group)
+	case len(found) > 1:
+		return nil, fmt.Errorf("posixGroup %s is ambiguous, more than one entry matches", group)
+	case found[0].GetAttributeValue(GIDNumberAttribute) == "":
+		return nil, fmt.Errorf("posixGroup %s has no gidNumber", group)
+	}
+	return found[0], nil
+}
+
+// firstValue returns the first value of name, the attribute name is matched case-insensitively.
+func firstValue(attrs map[string][]string, name string) string {
+	for key, values := range attrs {
+		if strings.EqualFold(key, name) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// setValue replaces the values of name, keeping the spelling of an existing key.
+func setValue(attrs map[string][]string, name, value string) {
+	for key := range attrs {
+		if strings.EqualFold(key, name) {
+			attrs[key] = []string{value}
+			return
+		}
+	}
+	attrs[name] = []string{value}
+}
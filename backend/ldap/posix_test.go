@@ -0,0 +1,146 @@
+package ldap
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseIDRange(t *testing.T) {
+	if r, err := ParseIDRange(" 1000 - 1999 "); err != nil || r != (IDRange{Min: 1000, Max: 1999}) {
+		t.Errorf("get %v %v", r, err)
+	}
+	for _, value := range []string{"1000", "a-b", "2000-1000", "-1-5"} {
+		if _, err := ParseIDRange(value); err == nil {
+			t.Errorf("expect error for %q", value)
+		}
+	}
+}
+
+func accountDirectory() *memoryDirectory {
+	return &memoryDirectory{entries: map[string]map[string][]string{
+		"uid=alice,ou=person,dc=example,dc=com": {"objectClass": {"posixAccount"}, "uid": {"alice"}, "uidNumber": {"10000"}, "gidNumber": {"100"}},
+		"uid=bob,ou=person,dc=example,dc=com":   {"objectClass": {"posixAccount"}, "uid": {"bob"}, "uidNumber": {"10005"}, "gidNumber": {"100"}},
+		"uid=root,ou=person,dc=example,dc=com":  {"objectClass": {"posixAccount"}, "uid": {"root"}, "uidNumber": {"0"}, "gidNumber": {"0"}},
+		"cn=users,ou=group,dc=example,dc=com":   {"objectClass": {"posixGroup"}, "cn": {"users"}, "gidNumber": {"100"}},
+		"cn=alice,ou=group,dc=example,dc=com":   {"objectClass": {"posixGroup"}, "cn": {"alice"}, "gidNumber": {"20000"}},
+	}}
+}
+
+func TestAllocateIDs(t *testing.T) {
+	accounts := &Accounts{
+		Op:         accountDirectory(),
+		Base:       "dc=example,dc=com",
+		UIDNumbers: IDRange{Min: 10000, Max: 10006},
+		GIDNumbers: IDRange{Min: 20000, Max: 29999},
+	}
+	if uid, err := accounts.NextUIDNumber(); err != nil || uid != 10006 {
+		t.Errorf("expect uidNumber 10006 after the highest one, get %d %v", uid, err)
+	}
+	if gid, err := accounts.NextGIDNumber(); err != nil || gid != 20001 {
+		t.Errorf("expect gidNumber 20001, get %d %v", gid, err)
+	}
+	accounts.UIDNumbers = IDRange{Min: 10000, Max: 10005}
+	if _, err := accounts.NextUIDNumber(); !errors.Is(err, ErrIDRangeExhausted) {
+		t.Errorf("expect ErrIDRangeExhausted, get %v", err)
+	}
+	accounts.UIDNumbers = IDRange{Min: 30000, Max: 39999}
+	if uid, err := accounts.NextUIDNumber(); err != nil || uid != 30000 {
+		t.Errorf("expect the start of an unused range, get %d %v", uid, err)
+	}
+}
+
+func TestAllocateIDsFromPool(t *testing.T) {
+	dir := accountDirectory()
+	pool := "cn=idpool,dc=example,dc=com"
+	dir.entries[pool] = map[string][]string{"objectClass": {"sambaUnixIdPool"}, "uidNumber": {"10004"}, "gidNumber": {"20000"}}
+	accounts := &Accounts{
+		Op:         dir,
+		Base:       "dc=example,dc=com",
+		UIDNumbers: IDRange{Min: 10000, Max: 19999},
+		GIDNumbers: IDRange{Min: 20000, Max: 29999},
+		IDPoolDN:   pool,
+	}
+
+	uid, err := accounts.NextUIDNumber()
+	if err != nil || uid != 10004 {
+		t.Fatalf("expect uidNumber 10004 from the pool, get %d %v", uid, err)
+	}
+	// 10005 is taken outside the pool
+	if uid, err = accounts.NextUIDNumber(); err != nil || uid != 10006 {
+		t.Fatalf("expect uidNumber 10006, get %d %v", uid, err)
+	}
+	if value := dir.entries[pool]["uidNumber"]; !slices.Equal(value, []string{"10007"}) {
+		t.Errorf("expect the pool at 10007, get %v", value)
+	}
+
+	// another allocator takes 10007 between our read and our modify
+	raced := false
+	dir.onModify = func(dn string) {
+		if !raced {
+			raced = true
+			dir.entries[pool]["uidNumber"] = []string{"10008"}
+		}
+	}
+	if uid, err = accounts.NextUIDNumber(); err != nil || uid != 10008 {
+		t.Errorf("expect uidNumber 10008 after the retry, get %d %v", uid, err)
+	}
+}
+
+func TestCreatePosixUser(t *testing.T) {
+	tmpl := &EntryTemplate{
+		Name:          "posix-user",
+		ObjectClasses: []string{"inetOrgPerson", "posixAccount"},
+		RDN:           "uid",
+		Parent:        "ou=person,{base}",
+		Required:      []string{"uid", "sn"},
+		Derived:       map[string]string{"cn": "{uid} {sn}"},
+	}
+	dir := accountDirectory()
+	accounts := &Accounts{
+		Op:            dir,
+		Base:          "dc=example,dc=com",
+		UIDNumbers:    IDRange{Min: 10000, Max: 19999},
+		GIDNumbers:    IDRange{Min: 20000, Max: 29999},
+		HomeDirectory: "/home/{user}",
+		LoginShell:    "/bin/zsh",
+		GroupParent:   "ou=group,dc=example,dc=com",
+	}
+
+	account, err := accounts.CreateUser(tmpl, PosixAccount{Attributes: map[string][]string{"uid": {"carol"}, "sn": {"Smith"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.DN != "uid=carol,ou=person,dc=example,dc=com" || account.GroupDN != "cn=carol,ou=group,dc=example,dc=com" {
+		t.Errorf("unexpected account %+v", account)
+	}
+	for name, expect := range map[string]string{"uidNumber": "10006", "gidNumber": "20001", "homeDirectory": "/home/carol", "loginShell": "/bin/zsh", "cn": "carol Smith"} {
+		if value := firstValue(account.Attributes, name); value != expect {
+			t.Errorf("expect %s %s, get %s", name, expect, value)
+		}
+	}
+	if gid := dir.entries["cn=carol,ou=group,dc=example,dc=com"]["gidNumber"]; !slices.Equal(gid, []string{"20001"}) {
+		t.Errorf("expect the primary group with gidNumber 20001, get %v", gid)
+	}
+
+	account, err = accounts.CreateUser(tmpl, PosixAccount{Attributes: map[string][]string{"uid": {"dave"}, "sn": {"Jones"}, "homeDirectory": {"/srv/dave"}}, PrimaryGroup: "users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Group != nil || firstValue(account.Attributes, "gidNumber") != "100" || firstValue(account.Attributes, "homeDirectory") != "/srv/dave" {
+		t.Errorf("unexpected account with an existing group %+v", account)
+	}
+
+	// the group created for an account that can not be added is removed again
+	dir.changes = nil
+	if _, err := accounts.CreateUser(tmpl, PosixAccount{Attributes: map[string][]string{"uid": {"bob"}, "sn": {"Brown"}}}); err == nil {
+		t.Fatal("expect error for an existing account")
+	}
+	if expect := []string{"add entry cn=bob,ou=group,dc=example,dc=com", "delete entry cn=bob,ou=group,dc=example,dc=com"}; !slices.Equal(dir.changes, expect) {
+		t.Errorf("get changes %v, expect %v", dir.changes, expect)
+	}
+	if _, err := accounts.CreateUser(tmpl, PosixAccount{Attributes: map[string][]string{"uid": {"erin"}, "sn": {"Black"}}, PrimaryGroup: "staff"}); err == nil || !strings.Contains(err.Error(), "staff") {
+		t.Errorf("expect error for an unknown primary group, get %v", err)
+	}
+}
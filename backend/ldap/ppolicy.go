@@ -0,0 +1,309 @@
+package ldap
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+// PasswordPolicy is a pwdPolicy entry of draft-behera-ldap-password-policy,
+// as used by the OpenLDAP ppolicy overlay. Durations of 0 mean unlimited.
+type PasswordPolicy struct {
+	DN              string        `json:"dn"`
+	Source          string        `json:"source"`          // pwdPolicySubentry of the entry or the configured default
+	MaxAge          time.Duration `json:"maxAge"`          // pwdMaxAge
+	MinAge          time.Duration `json:"minAge"`          // pwdMinAge
+	ExpireWarning   time.Duration `json:"expireWarning"`   // pwdExpireWarning
+	GraceAuthNLimit int           `json:"graceAuthNLimit"` // pwdGraceAuthNLimit
+	MinLength       int           `json:"minLength"`       // pwdMinLength
+	InHistory       int           `json:"inHistory"`       // pwdInHistory
+	CheckQuality    int           `json:"checkQuality"`    // pwdCheckQuality: 0 off, 1 when possible, 2 enforced
+	Lockout         bool          `json:"lockout"`         // pwdLockout
+	LockoutDuration time.Duration `json:"lockoutDuration"` // pwdLockoutDuration
+	MaxFailure      int           `json:"maxFailure"`      // pwdMaxFailure
+	MustChange      bool          `json:"mustChange"`      // pwdMustChange after a reset
+	AllowUserChange bool          `json:"allowUserChange"` // pwdAllowUserChange
+	SafeModify      bool          `json:"safeModify"`      // pwdSafeModify, the old password is required
+}
+
+// MarshalJSON writes the durations in seconds, like the pwdPolicy attributes.
+func (p PasswordPolicy) MarshalJSON() ([]byte, error) {
+	type plain PasswordPolicy
+	return json.Marshal(struct {
+		plain
+		MaxAge          int64 `json:"maxAge"`
+		MinAge          int64 `json:"minAge"`
+		ExpireWarning   int64 `json:"expireWarning"`
+		LockoutDuration int64 `json:"lockoutDuration"`
+	}{
+		plain:           plain(p),
+		MaxAge:          int64(p.MaxAge / time.Second),
+		MinAge:          int64(p.MinAge / time.Second),
+		ExpireWarning:   int64(p.ExpireWarning / time.Second),
+		LockoutDuration: int64(p.LockoutDuration / time.Second),
+	})
+}
+
+// sources of the effective password policy
+const (
+	PolicyFromSubentry = "pwdPolicySubentry"
+	PolicyFromDefault  = "default"
+)
+
+var passwordPolicyAttributes = []string{
+	"pwdMaxAge", "pwdMinAge", "pwdExpireWarning", "pwdGraceAuthNLimit", "pwdMinLength",
+	"pwdInHistory", "pwdCheckQuality", "pwdLockout", "pwdLockoutDuration", "pwdMaxFailure",
+	"pwdMustChange", "pwdAllowUserChange", "pwdSafeModify",
+}
+
+// newPasswordPolicy reads the policy from its entry, missing attributes keep their zero value.
+func newPasswordPolicy(entry *gldap.Entry) *PasswordPolicy {
+	number := func(name string) int {
+		n, _ := strconv.Atoi(entry.GetEqualFoldAttributeValue(name))
+		return n
+	}
+	seconds := func(name string) time.Duration {
+		return time.Duration(number(name)) * time.Second
+	}
+	boolean := func(name string, fallback bool) bool {
+		switch strings.ToUpper(entry.GetEqualFoldAttributeValue(name)) {
+		case "TRUE":
+			return true
+		case "FALSE":
+			return false
+		}
+		return fallback
+	}
+	return &PasswordPolicy{
+		DN:              entry.DN,
+		MaxAge:          seconds("pwdMaxAge"),
+		MinAge:          seconds("pwdMinAge"),
+		ExpireWarning:   seconds("pwdExpireWarning"),
+		GraceAuthNLimit: number("pwdGraceAuthNLimit"),
+		MinLength:       number("pwdMinLength"),
+		InHistory:       number("pwdInHistory"),
+		CheckQuality:    number("pwdCheckQuality"),
+		Lockout:         boolean("pwdLockout", false),
+		LockoutDuration: seconds("pwdLockoutDuration"),
+		MaxFailure:      number("pwdMaxFailure"),
+		MustChange:      boolean("pwdMustChange", false),
+		AllowUserChange: boolean("pwdAllowUserChange", true),
+		SafeModify:      boolean("pwdSafeModify", false),
+	}
+}
+
+// PasswordPolicy reads the policy entry at dn.
+func (op *LDAPOperation) PasswordPolicy(dn string) (*PasswordPolicy, error) {
+	page, err := op.SearchPaged(SearchOptions{
+		BaseDN:     dn,
+		Scope:      "base",
+		Filter:     "(objectClass=*)",
+		Attributes: passwordPolicyAttributes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(page.Entries) == 0 {
+		return nil, gldap.NewError(gldap.LDAPResultNoSuchObject, errors.New("password policy "+dn+" not found"))
+	}
+	return newPasswordPolicy(page.Entries[0]), nil
+}
+
+// EffectivePasswordPolicy returns the policy governing dn: its
+// pwdPolicySubentry, otherwise Profile.PasswordPolicyDN. It is nil when
+// neither is set.
+func (op *LDAPOperation) EffectivePasswordPolicy(dn string) (*PasswordPolicy, error) {
+	page, err := op.SearchPaged(SearchOptions{
+		BaseDN:     dn,
+		Scope:      "base",
+		Filter:     "(objectClass=*)",
+		Attributes: []string{"pwdPolicySubentry"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(page.Entries) == 0 {
+		return nil, gldap.NewError(gldap.LDAPResultNoSuchObject, errors.New(dn+" not found"))
+	}
+	return op.policyOf(page.Entries[0])
+}
+
+// policyOf reads the policy named by the pwdPolicySubentry of entry or the default one.
+func (op *LDAPOperation) policyOf(entry *gldap.Entry) (*PasswordPolicy, error) {
+	policyDN, source := entry.GetEqualFoldAttributeValue("pwdPolicySubentry"), PolicyFromSubentry
+	if policyDN == "" && op.Profile.PasswordPolicyDN != "" {
+		base, err := op.BaseDN()
+		if err != nil {
+			return nil, err
+		}
+		policyDN, source = op.Profile.expand(op.Profile.PasswordPolicyDN, base, ""), PolicyFromDefault
+	}
+	if policyDN == "" {
+		return nil, nil
+	}
+	policy, err := op.PasswordPolicy(policyDN)
+	if err != nil {
+		return nil, err
+	}
+	policy.Source = source
+	return policy, nil
+}
+
+// PolicyResponse is the password policy response control of a bind or a
+// password change, the Behera draft one of OpenLDAP or the older Netscape
+// ones of 389 Directory Server.
+type PolicyResponse struct {
+	Error           string `json:"error,omitempty"` // e.g. passwordTooShort, see policyErrors
+	Message         string `json:"message,omitempty"`
+	Expired         bool   `json:"expired"`
+	MustChange      bool   `json:"mustChange"`
+	ExpiresIn       int64  `json:"expiresIn,omitempty"` // seconds until the password expires
+	GraceLoginsLeft *int64 `json:"graceLoginsLeft,omitempty"`
+}
+
+// policyErrors names the error codes of the Behera draft.
+var policyErrors = map[int8]string{
+	gldap.BeheraPasswordExpired:             "passwordExpired",
+	gldap.BeheraAccountLocked:               "accountLocked",
+	gldap.BeheraChangeAfterReset:            "changeAfterReset",
+	gldap.BeheraPasswordModNotAllowed:       "passwordModNotAllowed",
+	gldap.BeheraMustSupplyOldPassword:       "mustSupplyOldPassword",
+	gldap.BeheraInsufficientPasswordQuality: "insufficientPasswordQuality",
+	gldap.BeheraPasswordTooShort:            "passwordTooShort",
+	gldap.BeheraPasswordTooYoung:            "passwordTooYoung",
+	gldap.BeheraPasswordInHistory:           "passwordInHistory",
+}
+
+// NewPolicyResponse decodes the password policy controls among controls, nil when there is none.
+func NewPolicyResponse(controls []gldap.Control) *PolicyResponse {
+	var response *PolicyResponse
+	get := func() *PolicyResponse {
+		if response == nil {
+			response = &PolicyResponse{}
+		}
+		return response
+	}
+	for _, control := range controls {
+		switch control := control.(type) {
+		case *gldap.ControlBeheraPasswordPolicy:
+			r := get()
+			if control.Expire >= 0 {
+				r.ExpiresIn = control.Expire
+			}
+			if control.Grace >= 0 {
+				grace := control.Grace
+				r.GraceLoginsLeft = &grace
+			}
+			if control.Error >= 0 {
+				r.Error, r.Message = policyErrors[control.Error], control.ErrorString
+				r.Expired = r.Expired || control.Error == gldap.BeheraPasswordExpired
+				r.MustChange = r.MustChange || control.Error == gldap.BeheraChangeAfterReset
+			}
+		case *gldap.ControlVChuPasswordMustChange:
+			if control.MustChange {
+				r := get()
+				r.MustChange = true
+			}
+		case *gldap.ControlVChuPasswordWarning:
+			if control.Expire >= 0 {
+				r := get()
+				r.ExpiresIn = control.Expire
+			}
+		}
+	}
+	if response != nil && response.Message == "" && response.MustChange {
+		response.Message = "Password must be changed"
+	}
+	return response
+}
+
+// PolicyError is a bind or password change refused for a reason the password policy tells.
+type PolicyError struct {
+	Policy *PolicyResponse
+	Err    error
+}
+
+func (e *PolicyError) Error() string {
+	return e.Policy.Message + ": " + e.Err.Error()
+}
+
+func (e *PolicyError) Unwrap() error {
+	return e.Err
+}
+
+// policyControls asks the server for the password policy state.
+func policyControls() []gldap.Control {
+	return []gldap.Control{gldap.NewControlBeheraPasswordPolicy()}
+}
+
+// withPolicy explains err by the policy response, read from the controls or
+// from the response packet kept in the LDAP error.
+func withPolicy(err error, controls []gldap.Control) error {
+	if err == nil {
+		return nil
+	}
+	if len(controls) == 0 {
+		controls = responseControls(err)
+	}
+	if policy := NewPolicyResponse(controls); policy != nil && policy.Message != "" {
+		return &PolicyError{Policy: policy, Err: err}
+	}
+	return err
+}
+
+// responseControls decodes the controls of the response carried by an LDAP error.
+func responseControls(err error) []gldap.Control {
+	var ldapErr *gldap.Error
+	if !errors.As(err, &ldapErr) || ldapErr.Packet == nil || len(ldapErr.Packet.Children) < 3 {
+		return nil
+	}
+	var controls []gldap.Control
+	for _, child := range ldapErr.Packet.Children[2].Children {
+		if control, err := gldap.DecodeControl(child); err == nil {
+			controls = append(controls, control)
+		}
+	}
+	return controls
+}
+
+// bindWithPolicy binds conn as dn asking for the password policy state. The
+// state is returned for a successful bind too, e.g. the grace logins left.
+func bindWithPolicy(conn *gldap.Conn, dn, password string) (*PolicyResponse, error) {
+	result, err := conn.SimpleBind(gldap.NewSimpleBindRequest(dn, password, policyControls()))
+	var controls []gldap.Control
+	if result != nil {
+		controls = result.Controls
+	}
+	return NewPolicyResponse(controls), withPolicy(err, controls)
+}
+
+// passwordModify sends the Password Modify extended operation with the
+// password policy control, which the PasswordModify of go-ldap can not carry.
+func passwordModify(conn *gldap.Conn, dn, oldPassword, newPassword string) error {
+	value := ber.Encode(ber.ClassContext, ber.TypePrimitive, 1, nil, "Extended Request Value: Password Modify Request")
+	request := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Password Modify Request")
+	if dn != "" {
+		request.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 0, dn, "User Identity"))
+	}
+	if oldPassword != "" {
+		request.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 1, oldPassword, "Old Password"))
+	}
+	request.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 2, newPassword, "New Password"))
+	value.AppendChild(request)
+
+	extended := gldap.NewExtendedRequest(PasswordModifyOID, value)
+	extended.Controls = policyControls()
+	_, err := conn.Extended(extended)
+	// RFC 3062 omits the responseName, go-ldap then reports a successful
+	// response as malformed; it checks the result code first
+	var ldapErr *gldap.Error
+	if err != nil && !errors.As(err, &ldapErr) && strings.Contains(err.Error(), "malformed extended response") {
+		return nil
+	}
+	return withPolicy(err, nil)
+}
@@ -0,0 +1,105 @@
+package ldap
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+// controls of a response as sent by OpenLDAP, see the control tests of go-ldap
+var (
+	policyTooShort = []byte{0xa0, 0x24, 0x30, 0x22, 0x4, 0x19, 0x31, 0x2e, 0x33, 0x2e, 0x36, 0x2e, 0x31, 0x2e, 0x34, 0x2e, 0x31, 0x2e, 0x34, 0x32, 0x2e, 0x32, 0x2e, 0x32, 0x37, 0x2e, 0x38, 0x2e, 0x35, 0x2e, 0x31, 0x4, 0x5, 0x30, 0x3, 0x81, 0x1, 0x6}
+	policyGrace17  = []byte{0xa0, 0x26, 0x30, 0x24, 0x4, 0x19, 0x31, 0x2e, 0x33, 0x2e, 0x36, 0x2e, 0x31, 0x2e, 0x34, 0x2e, 0x31, 0x2e, 0x34, 0x32, 0x2e, 0x32, 0x2e, 0x32, 0x37, 0x2e, 0x38, 0x2e, 0x35, 0x2e, 0x31, 0x4, 0x7, 0x30, 0x5, 0xa0, 0x3, 0x81, 0x1, 0x11}
+)
+
+// responseError builds the error of a modify response with resultCode and the given controls.
+func responseError(t *testing.T, resultCode int64, controls []byte) error {
+	envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, 1, "MessageID"))
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, gldap.ApplicationModifyResponse, nil, "Modify Response")
+	response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, resultCode, "Result Code"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "Password fails quality checking policy", "Diagnostic Message"))
+	envelope.AppendChild(response)
+	if controls != nil {
+		envelope.AppendChild(ber.DecodePacket(controls))
+	}
+	// encode and decode so the packet looks like one read from the wire
+	err := gldap.GetLDAPError(ber.DecodePacket(envelope.Bytes()))
+	if err == nil {
+		t.Fatal("expect an error response")
+	}
+	return err
+}
+
+func TestPolicyResponse(t *testing.T) {
+	if response := NewPolicyResponse(nil); response != nil {
+		t.Errorf("expect no response without controls, get %+v", response)
+	}
+
+	control, err := gldap.DecodeControl(ber.DecodePacket(policyGrace17).Children[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := NewPolicyResponse([]gldap.Control{control})
+	if response == nil || response.GraceLoginsLeft == nil || *response.GraceLoginsLeft != 17 || response.Error != "" {
+		t.Errorf("expect 17 grace logins, get %+v", response)
+	}
+
+	response = NewPolicyResponse([]gldap.Control{&gldap.ControlVChuPasswordMustChange{MustChange: true}, &gldap.ControlVChuPasswordWarning{Expire: 3600}})
+	if response == nil || !response.MustChange || response.ExpiresIn != 3600 || response.Message != "Password must be changed" {
+		t.Errorf("unexpected response of the Netscape controls %+v", response)
+	}
+}
+
+func TestWithPolicy(t *testing.T) {
+	err := withPolicy(responseError(t, gldap.LDAPResultConstraintViolation, policyTooShort), nil)
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("expect a PolicyError, get %v", err)
+	}
+	if policyErr.Policy.Error != "passwordTooShort" || !strings.HasPrefix(err.Error(), "Password is too short for policy: ") {
+		t.Errorf("unexpected policy error %q %+v", err, policyErr.Policy)
+	}
+	if !gldap.IsErrorWithCode(err, gldap.LDAPResultConstraintViolation) {
+		t.Errorf("the LDAP error should be kept, get %v", err)
+	}
+
+	// without a policy control the error stays as it is
+	plain := responseError(t, gldap.LDAPResultInvalidCredentials, nil)
+	if err := withPolicy(plain, nil); err != plain {
+		t.Errorf("expect the original error, get %v", err)
+	}
+	if withPolicy(nil, nil) != nil {
+		t.Error("expect nil for success")
+	}
+}
+
+func TestPasswordPolicyEntry(t *testing.T) {
+	policy := newPasswordPolicy(gldap.NewEntry("cn=default,ou=policies,dc=example,dc=com", map[string][]string{
+		"pwdMaxAge":        {"7776000"},
+		"pwdMinLength":     {"12"},
+		"pwdInHistory":     {"5"},
+		"pwdLockout":       {"TRUE"},
+		"pwdMustChange":    {"TRUE"},
+		"pwdSafeModify":    {"FALSE"},
+		"pwdCheckQuality":  {"2"},
+		"pwdExpireWarning": {"604800"},
+	}))
+	if policy.MaxAge != 90*24*time.Hour || policy.MinLength != 12 || policy.InHistory != 5 || !policy.Lockout ||
+		!policy.MustChange || policy.SafeModify || !policy.AllowUserChange || policy.CheckQuality != 2 {
+		t.Errorf("unexpected policy %+v", policy)
+	}
+	content, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), `"maxAge":7776000`) || !strings.Contains(string(content), `"expireWarning":604800`) {
+		t.Errorf("expect durations in seconds, get %s", content)
+	}
+}
@@ -0,0 +1,134 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+// ServerProfile describes the layout of the directory we manage.
+// AdminDN, UserDNTemplate and UserFilter may contain the placeholders
+// {base} (the naming context) and {user} (the login name).
+type ServerProfile struct {
+	BaseDN         string `json:"baseDN"` // discovered from the root DSE when empty
+	AdminDN        string `json:"adminDN"`
+	UserDNTemplate string `json:"userDNTemplate"` // empty means search-then-bind with UserFilter
+	UserFilter     string `json:"userFilter"`
+	LookupDN       string `json:"lookupDN"` // account used for search-then-bind, anonymous when empty
+	LookupPassword string `json:"-"`
+	PasswordHash   string `json:"passwordHash"` // hash of userPassword when the server lacks Password Modify
+	// member kept in an otherwise empty groupOfNames, which needs one; empty refuses to remove the last member
+	EmptyGroupMember string  `json:"emptyGroupMember"`
+	UIDNumbers       IDRange `json:"uidNumbers"` // allocated to new posix accounts
+	GIDNumbers       IDRange `json:"gidNumbers"`
+	IDPoolDN         string  `json:"idPoolDN"`      // sambaUnixIdPool entry counting the next numbers, empty searches the used ones
+	HomeDirectory    string  `json:"homeDirectory"` // {user} is replaced by the uid
+	LoginShell       string  `json:"loginShell"`
+	GroupParent      string  `json:"groupParent"`      // where user private groups are created
+	AccountLock      string  `json:"accountLock"`      // ppolicy, 389ds or ad, empty detects it from the server
+	MemberOf         string  `json:"memberOf"`         // on or off, empty detects it from the memberOf values of the entries
+	PasswordPolicyDN string  `json:"passwordPolicyDN"` // ppolicy default policy, used for entries without pwdPolicySubentry
+	SkipValidation   bool    `json:"skipValidation"`   // send adds and modifies without checking them against the schema
+}
+
+func DefaultServerProfile() ServerProfile {
+	return ServerProfile{
+		AdminDN:          "cn=admin,{base}",
+		UserDNTemplate:   "uid={user},ou=person,{base}",
+		UserFilter:       "(|(uid={user})(mail={user}))",
+		PasswordHash:     HashSSHA,
+		EmptyGroupMember: "cn=nobody,{base}",
+		UIDNumbers:       IDRange{Min: 10000, Max: 59999},
+		GIDNumbers:       IDRange{Min: 10000, Max: 59999},
+		HomeDirectory:    "/home/{user}",
+		LoginShell:       "/bin/bash",
+		GroupParent:      "ou=group,{base}",
+	}
+}
+
+func (p *ServerProfile) expand(template, base, user string) string {
+	return strings.NewReplacer("{base}", base, "{user}", user).Replace(template)
+}
+
+// BaseDN returns the configured naming context, discovering it from the root DSE when not set.
+func (op *LDAPOperation) BaseDN() (string, error) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	if op.Profile.BaseDN != "" {
+		return op.Profile.BaseDN, nil
+	}
+	dse, err := op.readRootDSE()
+	if err != nil {
+		return "", fmt.Errorf("discover naming context: %w", err)
+	}
+	base := dse.BaseNamingContext()
+	if base == "" {
+		return "", errors.New("server publishes no naming context, please configure a base DN")
+	}
+	op.Profile.BaseDN = base
+	return base, nil
+}
+
+// AdminDN returns the bind DN of the administrator.
+func (op *LDAPOperation) AdminDN() (string, error) {
+	base, err := op.BaseDN()
+	if err != nil {
+		return "", err
+	}
+	return op.Profile.expand(op.Profile.AdminDN, base, ""), nil
+}
+
+// resolveBindDN maps the login name to the DN to bind with. A login that is
+// already a DN is used as is.
+func (op *LDAPOperation) resolveBindDN() (string, error) {
+	user := op.OriginUser
+	if strings.Contains(user, "=") {
+		return user, nil
+	}
+	if user == "admin" && op.Profile.AdminDN != "" {
+		return op.AdminDN()
+	}
+	base, err := op.BaseDN()
+	if err != nil {
+		return "", err
+	}
+	if op.Profile.UserDNTemplate != "" {
+		return op.Profile.expand(op.Profile.UserDNTemplate, base, gldap.EscapeDN(user)), nil
+	}
+	return op.lookupUserDN(base, user)
+}
+
+// lookupUserDN finds the entry of the user by UserFilter, the search-then-bind way.
+func (op *LDAPOperation) lookupUserDN(base, user string) (string, error) {
+	if op.Profile.UserFilter == "" {
+		return "", errors.New("neither a user DN template nor a user filter is configured")
+	}
+	if op.Profile.LookupDN != "" {
+		if err := op.Conn.Bind(op.Profile.LookupDN, op.Profile.LookupPassword); err != nil {
+			return "", fmt.Errorf("lookup bind failed: %w", err)
+		}
+	}
+	filter := op.Profile.expand(op.Profile.UserFilter, base, gldap.EscapeFilter(user))
+	searchRequest := gldap.NewSearchRequest(
+		base,
+		gldap.ScopeWholeSubtree,
+		gldap.NeverDerefAliases,
+		2, 0, false,
+		filter,
+		[]string{"1.1"}, // no attributes, only the dn
+		nil,
+	)
+	result, err := op.Conn.Search(searchRequest)
+	if err != nil && !gldap.IsErrorWithCode(err, gldap.LDAPResultSizeLimitExceeded) {
+		return "", err
+	}
+	if result == nil || len(result.Entries) == 0 {
+		return "", fmt.Errorf("no entry found for user %s", user)
+	}
+	if len(result.Entries) > 1 {
+		return "", fmt.Errorf("user %s is ambiguous, more than one entry matches", user)
+	}
+	return result.Entries[0].DN, nil
+}
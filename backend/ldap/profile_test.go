@@ -0,0 +1,38 @@
+package ldap
+
+import "testing"
+
+func TestResolveBindDN(t *testing.T) {
+	values := []struct {
+		user   string
+		expect string
+	}{
+		{"admin", "cn=admin,dc=corp,dc=org"},
+		{"alice", "uid=alice,ou=person,dc=corp,dc=org"},
+		{"cn=manager,dc=corp,dc=org", "cn=manager,dc=corp,dc=org"},
+		{"uid=bob,ou=staff,dc=corp,dc=org", "uid=bob,ou=staff,dc=corp,dc=org"},
+		{"a,b", `uid=a\,b,ou=person,dc=corp,dc=org`},
+	}
+	for _, value := range values {
+		op, _ := NewLDAPOperation(value.user, "secret", "localhost", 389)
+		op.Profile.BaseDN = "dc=corp,dc=org"
+		dn, err := op.resolveBindDN()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dn != value.expect {
+			t.Errorf("get value: %s, expect: %s", dn, value.expect)
+		}
+	}
+}
+
+func TestBaseNamingContext(t *testing.T) {
+	dse := RootDSE{NamingContexts: []string{"cn=config", "dc=corp,dc=org"}}
+	if base := dse.BaseNamingContext(); base != "dc=corp,dc=org" {
+		t.Errorf("get value: %s, expect: dc=corp,dc=org", base)
+	}
+	dse.DefaultNamingContext = "DC=ad,DC=corp"
+	if base := dse.BaseNamingContext(); base != "DC=ad,DC=corp" {
+		t.Errorf("get value: %s, expect: DC=ad,DC=corp", base)
+	}
+}
@@ -0,0 +1,137 @@
+package ldap
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+// RootDSE holds the server information published on the empty DN.
+type RootDSE struct {
+	NamingContexts        []string `json:"namingContexts"`
+	DefaultNamingContext  string   `json:"defaultNamingContext"`
+	SubschemaSubentry     string   `json:"subschemaSubentry"`
+	SupportedControls     []string `json:"supportedControl"`
+	SupportedExtensions   []string `json:"supportedExtension"`
+	SupportedCapabilities []string `json:"supportedCapabilities"`
+	VendorName            string   `json:"vendorName"`
+	VendorVersion         string   `json:"vendorVersion"`
+	ObjectClasses         []string `json:"objectClass"`
+}
+
+var rootDSEAttributes = []string{
+	"namingContexts", "defaultNamingContext", "subschemaSubentry",
+	"supportedControl", "supportedExtension", "supportedCapabilities",
+	"vendorName", "vendorVersion", "objectClass",
+}
+
+// RootDSE reads the root DSE once per connection.
+func (op *LDAPOperation) RootDSE() (*RootDSE, error) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	return op.readRootDSE()
+}
+
+// readRootDSE expects op.mu to be held.
+func (op *LDAPOperation) readRootDSE() (*RootDSE, error) {
+	if op.rootDSE != nil {
+		return op.rootDSE, nil
+	}
+	if op.Conn == nil {
+		return nil, errors.New("LDAP connection is not established")
+	}
+	searchRequest := gldap.NewSearchRequest(
+		"",
+		gldap.ScopeBaseObject,
+		gldap.NeverDerefAliases,
+		0, 0, false,
+		"(objectClass=*)",
+		rootDSEAttributes,
+		nil,
+	)
+	result, err := op.Conn.Search(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Entries) == 0 {
+		return nil, errors.New("root DSE is not readable")
+	}
+	entry := result.Entries[0]
+	op.rootDSE = &RootDSE{
+		NamingContexts:        entry.GetAttributeValues("namingContexts"),
+		DefaultNamingContext:  entry.GetAttributeValue("defaultNamingContext"),
+		SubschemaSubentry:     entry.GetAttributeValue("subschemaSubentry"),
+		SupportedControls:     entry.GetAttributeValues("supportedControl"),
+		SupportedExtensions:   entry.GetAttributeValues("supportedExtension"),
+		SupportedCapabilities: entry.GetAttributeValues("supportedCapabilities"),
+		VendorName:            entry.GetAttributeValue("vendorName"),
+		VendorVersion:         entry.GetAttributeValue("vendorVersion"),
+		ObjectClasses:         entry.GetAttributeValues("objectClass"),
+	}
+	return op.rootDSE, nil
+}
+
+// BaseNamingContext picks the naming context to work under: the AD default
+// naming context when present, otherwise the first public one.
+func (d *RootDSE) BaseNamingContext() string {
+	if d.DefaultNamingContext != "" {
+		return d.DefaultNamingContext
+	}
+	for _, nc := range d.NamingContexts {
+		// OpenLDAP lists cn=config and cn=monitor only to rootdn, skip them anyway
+		lower := strings.ToLower(nc)
+		if lower == "cn=config" || lower == "cn=monitor" || lower == "" {
+			continue
+		}
+		return nc
+	}
+	return ""
+}
+
+func (d *RootDSE) SupportsControl(oid string) bool {
+	return slices.Contains(d.SupportedControls, oid)
+}
+
+func (d *RootDSE) SupportsExtension(oid string) bool {
+	return slices.Contains(d.SupportedExtensions, oid)
+}
+
+// defaultSubschemaDN is tried when the server does not publish subschemaSubentry.
+const defaultSubschemaDN = "cn=subschema"
+
+// SubschemaDN finds the subschema entry governing the directory (RFC 4512
+// 4.2): the subschemaSubentry of the base entry when present, then the one
+// of the root DSE. OpenLDAP uses cn=Subschema, 389-DS cn=schema and Active
+// Directory CN=Aggregate,CN=Schema,CN=Configuration,...
+func (op *LDAPOperation) SubschemaDN() (string, error) {
+	if op.Conn == nil {
+		return "", errors.New("LDAP connection is not established")
+	}
+	if base, err := op.BaseDN(); err == nil && base != "" {
+		searchRequest := gldap.NewSearchRequest(
+			base,
+			gldap.ScopeBaseObject,
+			gldap.NeverDerefAliases,
+			0, 0, false,
+			"(objectClass=*)",
+			[]string{"subschemaSubentry"},
+			nil,
+		)
+		// the attribute is operational, some servers hide it from the entry
+		if result, err := op.Conn.Search(searchRequest); err == nil && len(result.Entries) > 0 {
+			if dn := result.Entries[0].GetAttributeValue("subschemaSubentry"); dn != "" {
+				return dn, nil
+			}
+		}
+	}
+	dse, err := op.RootDSE()
+	if err != nil {
+		return "", err
+	}
+	if dse.SubschemaSubentry != "" {
+		return dse.SubschemaSubentry, nil
+	}
+	return defaultSubschemaDN, nil
+}
@@ -0,0 +1,210 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+// states of a cached schema
+const (
+	SchemaUnloaded = "unloaded"
+	SchemaLoading  = "loading"
+	SchemaReady    = "ready"
+	SchemaFailed   = "failed"
+)
+
+// SchemaStatus reports the state of the schema of one server.
+type SchemaStatus struct {
+	State           string    `json:"state"`
+	SubschemaDN     string    `json:"subschemaDN,omitempty"`
+	ModifyTimestamp string    `json:"modifyTimestamp,omitempty"`
+	LoadedAt        time.Time `json:"loadedAt,omitempty"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// SchemaCache shares the parsed schema between the sessions of the same
+// server and naming context. A cached schema is reused as long as the
+// modifyTimestamp of the subschema entry does not change.
+type SchemaCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedSchema
+}
+
+type cachedSchema struct {
+	load   sync.Mutex // one load per server at a time
+	parser *ObjectClassParser
+	status SchemaStatus // guarded by SchemaCache.mu
+}
+
+// schemaReader reads the subschema entry of a server, an LDAPOperation reads
+// it over its connection.
+type schemaReader interface {
+	SubschemaDN() (string, error)
+	schemaTimestamp(schemaDN string) (string, error)
+	readSchema(schemaDN string) (*ObjectClassParser, string, error)
+}
+
+func NewSchemaCache() *SchemaCache {
+	return &SchemaCache{entries: make(map[string]*cachedSchema)}
+}
+
+// schemaKey identifies the server and naming context op works on.
+func (op *LDAPOperation) schemaKey() string {
+	base, _ := op.BaseDN()
+	return fmt.Sprintf("%s:%d/%s", strings.ToLower(op.Host), op.Port, strings.ToLower(base))
+}
+
+func (c *SchemaCache) entry(key string) *cachedSchema {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, exist := c.entries[key]
+	if !exist {
+		entry = &cachedSchema{status: SchemaStatus{State: SchemaUnloaded}}
+		c.entries[key] = entry
+	}
+	return entry
+}
+
+func (c *SchemaCache) setStatus(entry *cachedSchema, update func(*SchemaStatus)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	update(&entry.status)
+}
+
+// Status returns the state of the schema of the server op is connected to.
+func (c *SchemaCache) Status(op *LDAPOperation) SchemaStatus {
+	entry := c.entry(op.schemaKey())
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return entry.status
+}
+
+// Load returns the schema of the server op is connected to. The schema is
+// read again when force is set or the subschema entry changed since the last
+// load; concurrent loads of the same server wait for each other.
+func (c *SchemaCache) Load(op *LDAPOperation, force bool) (*ObjectClassParser, error) {
+	reader := op.schemaSource
+	if reader == nil {
+		if op.Conn == nil {
+			return nil, errors.New("LDAP connection is not established")
+		}
+		reader = op
+	}
+	entry := c.entry(op.schemaKey())
+	entry.load.Lock()
+	defer entry.load.Unlock()
+
+	schemaDN, err := reader.SubschemaDN()
+	if err != nil {
+		return entry.parser, err
+	}
+	// servers that do not publish modifyTimestamp keep the cached schema until a forced refresh
+	stamp, err := reader.schemaTimestamp(schemaDN)
+	if err != nil {
+		log.Println("read schema modifyTimestamp: ", err)
+	}
+	if !force && entry.parser != nil && (stamp == "" || stamp == entry.status.ModifyTimestamp) {
+		return entry.parser, nil
+	}
+
+	c.setStatus(entry, func(status *SchemaStatus) {
+		status.State = SchemaLoading
+		status.SubschemaDN = schemaDN
+		status.Error = ""
+	})
+	parser, stamp, err := reader.readSchema(schemaDN)
+	if err != nil {
+		c.setStatus(entry, func(status *SchemaStatus) {
+			status.State = SchemaFailed
+			status.Error = err.Error()
+			// an older schema is still served
+			if entry.parser != nil {
+				status.State = SchemaReady
+			}
+		})
+		return entry.parser, err
+	}
+	entry.parser = parser
+	c.setStatus(entry, func(status *SchemaStatus) {
+		status.State = SchemaReady
+		status.ModifyTimestamp = stamp
+		status.LoadedAt = time.Now()
+	})
+	return parser, nil
+}
+
+// schemaTimestamp reads the modifyTimestamp of the subschema entry, "" when not published.
+func (op *LDAPOperation) schemaTimestamp(schemaDN string) (string, error) {
+	searchRequest := gldap.NewSearchRequest(
+		schemaDN,
+		gldap.ScopeBaseObject,
+		gldap.NeverDerefAliases,
+		0, 0, false,
+		"(objectClass=subschema)",
+		[]string{"modifyTimestamp"},
+		nil,
+	)
+	result, err := op.Conn.Search(searchRequest)
+	if err != nil {
+		return "", err
+	}
+	if len(result.Entries) == 0 {
+		return "", nil
+	}
+	return result.Entries[0].GetAttributeValue("modifyTimestamp"), nil
+}
+
+// readSchema parses object classes, attribute types, matching rules and
+// syntaxes of the subschema entry into a new parser.
+func (op *LDAPOperation) readSchema(schemaDN string) (*ObjectClassParser, string, error) {
+	// RFC 4512 5.1 reads the subschema with this filter
+	searchRequest := gldap.NewSearchRequest(
+		schemaDN,
+		gldap.ScopeBaseObject,
+		gldap.NeverDerefAliases,
+		0, 0, false,
+		"(objectClass=subschema)",
+		[]string{"objectClasses", "attributeTypes", "matchingRules", "ldapSyntaxes", "modifyTimestamp"},
+		nil,
+	)
+	result, err := op.Conn.Search(searchRequest)
+	if err != nil {
+		return nil, "", fmt.Errorf("read schema from %s: %w", schemaDN, err)
+	}
+	if len(result.Entries) == 0 {
+		return nil, "", fmt.Errorf("no schema entry found at %s", schemaDN)
+	}
+	entry := result.Entries[0]
+	parser := NewObjectClassParser()
+	// a vendor specific definition must not hide the rest of the schema
+	for _, item := range entry.GetAttributeValues("ldapSyntaxes") {
+		if _, err := parser.ParseLDAPSyntax(item); err != nil {
+			log.Println("skip ldapSyntax: ", err)
+		}
+	}
+	for _, item := range entry.GetAttributeValues("matchingRules") {
+		if _, err := parser.ParseMatchingRule(item); err != nil {
+			log.Println("skip matchingRule: ", err)
+		}
+	}
+	for _, item := range entry.GetAttributeValues("attributeTypes") {
+		if _, err := parser.ParseAttributeType(item); err != nil {
+			log.Println("skip attributeType: ", err)
+		}
+	}
+	for _, item := range entry.GetAttributeValues("objectClasses") {
+		if _, err := parser.ParseObjectClass(item); err != nil {
+			log.Println("skip objectclass: ", err)
+		}
+	}
+	if !parser.Loaded() {
+		return nil, "", fmt.Errorf("no objectClasses found at %s", schemaDN)
+	}
+	return parser, entry.GetAttributeValue("modifyTimestamp"), nil
+}
@@ -0,0 +1,117 @@
+package ldap
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// stubSchemaReader serves a new schema on every read, its modifyTimestamp
+// changes with each call so every load replaces the cached snapshot.
+type stubSchemaReader struct {
+	snapshots []*ObjectClassParser
+	reads     atomic.Int64
+	stamps    atomic.Int64
+}
+
+func (s *stubSchemaReader) SubschemaDN() (string, error) {
+	return "cn=subschema", nil
+}
+
+func (s *stubSchemaReader) schemaTimestamp(schemaDN string) (string, error) {
+	return fmt.Sprintf("2024010100%04dZ", s.stamps.Add(1)), nil
+}
+
+func (s *stubSchemaReader) readSchema(schemaDN string) (*ObjectClassParser, string, error) {
+	n := s.reads.Add(1)
+	return s.snapshots[int(n)%len(s.snapshots)], fmt.Sprintf("2024010100%04dZ", s.stamps.Load()), nil
+}
+
+// TestSchemaConcurrentAccess reloads the schema through the shared cache
+// while other goroutines read and validate with it, run it with -race
+// (make test-race).
+func TestSchemaConcurrentAccess(t *testing.T) {
+	reader := &stubSchemaReader{snapshots: make([]*ObjectClassParser, 10)}
+	for i := range reader.snapshots {
+		reader.snapshots[i] = validatorSchema(t)
+	}
+	cache := NewSchemaCache()
+	// two sessions of the same server share the cache entry
+	sessions := make([]*LDAPOperation, 2)
+	for i := range sessions {
+		sessions[i], _ = NewLDAPOperation("admin", "secret", "localhost", 389)
+		sessions[i].Profile.BaseDN = "dc=example,dc=com"
+		sessions[i].Schemas = cache
+		sessions[i].schemaSource = reader
+	}
+	op := sessions[0]
+	if op.Schema().Loaded() {
+		t.Fatal("schema must be empty before loading")
+	}
+	entry := map[string][]string{"objectClass": {"inetOrgPerson"}, "cn": {"John"}, "sn": {"Doe"}}
+	changes := []AttributeChange{{Operation: ModifyReplace, Attribute: "displayName", Values: []string{"Johnny"}}}
+
+	var wg sync.WaitGroup
+	for _, session := range sessions {
+		wg.Add(1)
+		go func(session *LDAPOperation) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				var err error
+				if i%10 == 0 {
+					err = session.RefreshSchema()
+				} else {
+					err = session.GetObjectClassAttributes()
+				}
+				if err != nil {
+					t.Errorf("load schema: %v", err)
+					return
+				}
+			}
+		}(session)
+	}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(session *LDAPOperation) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				schema := session.Schema()
+				if !schema.Loaded() {
+					continue
+				}
+				if errs := schema.ValidateEntry(entry); len(errs) > 0 {
+					t.Errorf("unexpected problems %v", errs)
+					return
+				}
+				if errs := schema.ValidateModify(entry, changes); len(errs) > 0 {
+					t.Errorf("unexpected problems %v", errs)
+					return
+				}
+				if must, _ := session.GetObjAttrs("person"); len(must) != 2 {
+					t.Errorf("expect the MUST of person, get %v", must)
+					return
+				}
+				if base, err := session.BaseDN(); err != nil || base != "dc=example,dc=com" {
+					t.Errorf("unexpected base %s: %v", base, err)
+					return
+				}
+				cache.Status(session)
+			}
+		}(sessions[i%len(sessions)])
+	}
+	wg.Wait()
+
+	status := cache.Status(op)
+	if status.State != SchemaReady || status.SubschemaDN != "cn=subschema" || status.ModifyTimestamp == "" {
+		t.Errorf("expect a ready schema, get %+v", status)
+	}
+	if reader.reads.Load() != 200 {
+		t.Errorf("expect every load to read the changed schema, get %d reads", reader.reads.Load())
+	}
+	for _, session := range sessions {
+		if !session.Schema().Loaded() {
+			t.Error("every session must hold a snapshot")
+		}
+	}
+}
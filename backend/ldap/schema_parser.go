@@ -0,0 +1,276 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Tokenizer and generic reader for the schema description grammar of RFC 4512
+// section 4.1. The descriptions of object classes, attribute types, matching
+// rules and syntaxes all share the form
+//
+//	( oid KEYWORD argument ... X-EXTENSION qdstrings )
+//
+// and only differ in the keywords they allow.
+
+const (
+	tokenLParen = iota
+	tokenRParen
+	tokenDollar
+	tokenQuoted
+	tokenWord
+)
+
+type schemaToken struct {
+	kind  int
+	value string
+}
+
+func tokenizeSchema(input string) ([]schemaToken, error) {
+	var tokens []schemaToken
+	for i := 0; i < len(input); {
+		switch ch := input[i]; {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == '(':
+			tokens = append(tokens, schemaToken{kind: tokenLParen, value: "("})
+			i++
+		case ch == ')':
+			tokens = append(tokens, schemaToken{kind: tokenRParen, value: ")"})
+			i++
+		case ch == '$':
+			tokens = append(tokens, schemaToken{kind: tokenDollar, value: "$"})
+			i++
+		case ch == '\'':
+			end := strings.IndexByte(input[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quoted string at offset %d", i)
+			}
+			value, err := unescapeQDString(input[i+1 : i+1+end])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, schemaToken{kind: tokenQuoted, value: value})
+			i += end + 2
+		default:
+			start := i
+			for i < len(input) && !strings.ContainsRune(" \t\n\r()$'", rune(input[i])) {
+				i++
+			}
+			tokens = append(tokens, schemaToken{kind: tokenWord, value: input[start:i]})
+		}
+	}
+	return tokens, nil
+}
+
+// unescapeQDString decodes the \27 (quote) and \5C (backslash) escapes of a qdstring.
+func unescapeQDString(value string) (string, error) {
+	if !strings.Contains(value, `\`) {
+		return value, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			sb.WriteByte(value[i])
+			continue
+		}
+		if i+3 > len(value) {
+			return "", fmt.Errorf("truncated escape in %q", value)
+		}
+		code, err := strconv.ParseUint(value[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in %q", value)
+		}
+		sb.WriteByte(byte(code))
+		i += 2
+	}
+	return sb.String(), nil
+}
+
+// argument forms of a keyword
+const (
+	argNone     = iota // flag such as OBSOLETE or SINGLE-VALUE
+	argQDescrs         // 'name' or ( 'name1' 'name2' )
+	argQDString        // 'text'
+	argOID             // single oid, SYNTAX may carry a {length}
+	argOIDs            // oid or ( oid $ oid )
+	argWord            // bare word, e.g. USAGE userApplications
+)
+
+// schemaDescription is the generic result of reading one description.
+type schemaDescription struct {
+	OID        string
+	Values     map[string][]string // keyword to its arguments, flags map to nil
+	Extensions map[string][]string
+}
+
+func (d *schemaDescription) has(keyword string) bool {
+	_, exist := d.Values[keyword]
+	return exist
+}
+
+func (d *schemaDescription) first(keyword string) string {
+	if values := d.Values[keyword]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// parseSchemaDescription reads input using the allowed keywords and their argument forms.
+func parseSchemaDescription(input string, keywords map[string]int) (*schemaDescription, error) {
+	tokens, err := tokenizeSchema(input)
+	if err != nil {
+		return nil, err
+	}
+	s := &schemaScanner{tokens: tokens}
+	if !s.accept(tokenLParen) {
+		return nil, errors.New("description must start with (")
+	}
+	oid, ok := s.next()
+	if !ok || (oid.kind != tokenWord && oid.kind != tokenQuoted) {
+		return nil, errors.New("missing oid")
+	}
+	desc := &schemaDescription{
+		OID:        oid.value,
+		Values:     make(map[string][]string),
+		Extensions: make(map[string][]string),
+	}
+
+	for {
+		token, ok := s.next()
+		if !ok {
+			return nil, errors.New("missing closing )")
+		}
+		if token.kind == tokenRParen {
+			break
+		}
+		if token.kind != tokenWord {
+			return nil, fmt.Errorf("expect keyword, get %q", token.value)
+		}
+		keyword := strings.ToUpper(token.value)
+		if strings.HasPrefix(keyword, "X-") {
+			values, err := s.qdstrings()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", token.value, err)
+			}
+			desc.Extensions[token.value] = values
+			continue
+		}
+		form, known := keywords[keyword]
+		if !known {
+			return nil, fmt.Errorf("unknown keyword %s", token.value)
+		}
+		if desc.has(keyword) {
+			return nil, fmt.Errorf("keyword %s given twice", keyword)
+		}
+		var values []string
+		switch form {
+		case argQDescrs:
+			values, err = s.qdstrings()
+		case argQDString:
+			var value string
+			value, err = s.quoted()
+			values = []string{value}
+		case argOID, argWord:
+			var value string
+			value, err = s.word()
+			values = []string{value}
+		case argOIDs:
+			values, err = s.oids()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", keyword, err)
+		}
+		desc.Values[keyword] = values
+	}
+	if token, ok := s.next(); ok {
+		return nil, fmt.Errorf("unexpected %q after closing )", token.value)
+	}
+	return desc, nil
+}
+
+type schemaScanner struct {
+	tokens []schemaToken
+	pos    int
+}
+
+func (s *schemaScanner) next() (schemaToken, bool) {
+	if s.pos >= len(s.tokens) {
+		return schemaToken{}, false
+	}
+	s.pos++
+	return s.tokens[s.pos-1], true
+}
+
+func (s *schemaScanner) accept(kind int) bool {
+	if s.pos < len(s.tokens) && s.tokens[s.pos].kind == kind {
+		s.pos++
+		return true
+	}
+	return false
+}
+
+func (s *schemaScanner) quoted() (string, error) {
+	token, ok := s.next()
+	if !ok || token.kind != tokenQuoted {
+		return "", errors.New("expect quoted string")
+	}
+	return token.value, nil
+}
+
+// word reads an oid or bare word, some servers quote them so quoted is accepted too.
+func (s *schemaScanner) word() (string, error) {
+	token, ok := s.next()
+	if !ok || (token.kind != tokenWord && token.kind != tokenQuoted) {
+		return "", errors.New("expect oid")
+	}
+	return token.value, nil
+}
+
+// qdstrings reads 'a' or ( 'a' 'b' ).
+func (s *schemaScanner) qdstrings() ([]string, error) {
+	if !s.accept(tokenLParen) {
+		value, err := s.quoted()
+		if err != nil {
+			return nil, err
+		}
+		return []string{value}, nil
+	}
+	var values []string
+	for !s.accept(tokenRParen) {
+		value, err := s.quoted()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// oids reads oid or ( oid $ oid ), a missing $ between oids is tolerated.
+func (s *schemaScanner) oids() ([]string, error) {
+	if !s.accept(tokenLParen) {
+		value, err := s.word()
+		if err != nil {
+			return nil, err
+		}
+		return []string{value}, nil
+	}
+	var values []string
+	for !s.accept(tokenRParen) {
+		if len(values) > 0 {
+			s.accept(tokenDollar)
+		}
+		value, err := s.word()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	if len(values) == 0 {
+		return nil, errors.New("empty oid list")
+	}
+	return values, nil
+}
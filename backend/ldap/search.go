@@ -0,0 +1,168 @@
+package ldap
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+// ParseScope maps base, one and sub to the search scope, empty means sub.
+func ParseScope(scope string) (int, error) {
+	switch strings.ToLower(scope) {
+	case "base":
+		return gldap.ScopeBaseObject, nil
+	case "one", "onelevel":
+		return gldap.ScopeSingleLevel, nil
+	case "", "sub", "subtree":
+		return gldap.ScopeWholeSubtree, nil
+	}
+	return 0, fmt.Errorf("unknown scope %q, expect one of base, one, sub", scope)
+}
+
+// StreamSearch runs the search page by page with the Simple Paged Results
+// control and hands every entry to fn as soon as it arrives, so large results
+// neither hit the size limit of the server nor have to be held in memory.
+// Servers without paging answer the first request with the whole result.
+func (op *LDAPOperation) StreamSearch(ctx context.Context, baseDN, filter string, scope int, fn func(*gldap.Entry) error) error {
+	if op.Conn == nil {
+		return errors.New("LDAP connection is not established")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	paging := gldap.NewControlPaging(DefaultPageSize)
+	searchRequest := gldap.NewSearchRequest(
+		baseDN,
+		scope,
+		gldap.NeverDerefAliases,
+		0, 0, false,
+		filter,
+		nil,
+		[]gldap.Control{paging},
+	)
+	for {
+		var cookie []byte
+		response := op.Conn.SearchAsync(ctx, searchRequest, 64)
+		for response.Next() {
+			entry := response.Entry()
+			if entry == nil {
+				// referral or the controls of the final result
+				if control, ok := gldap.FindControl(response.Controls(), gldap.ControlTypePaging).(*gldap.ControlPaging); ok {
+					cookie = control.Cookie
+				}
+				continue
+			}
+			if err := fn(entry); err != nil {
+				// stop the search and let the reader goroutine finish
+				cancel()
+				for response.Next() {
+				}
+				return err
+			}
+		}
+		if err := response.Err(); err != nil {
+			return err
+		}
+		if len(cookie) == 0 {
+			return nil
+		}
+		paging.SetCookie(cookie)
+	}
+}
+
+// DefaultPageSize is the page size used when whole results are fetched page by page.
+const DefaultPageSize = 500
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SearchOptions describes a search. Scope and DerefAliases take the names
+// accepted by ParseScope and ParseDeref, PageSize 0 disables paging.
+type SearchOptions struct {
+	BaseDN       string
+	Scope        string
+	Filter       string
+	Attributes   []string // empty requests all user attributes
+	SizeLimit    int
+	TimeLimit    int // seconds
+	DerefAliases string
+	PageSize     uint32
+	Cursor       string // opaque cursor of the previous page, empty for the first page
+}
+
+// SearchPage is one page of entries, Cursor is empty on the last page.
+// Truncated is set when the size or time limit cut the result.
+type SearchPage struct {
+	Entries   []*gldap.Entry `json:"entries"`
+	Cursor    string         `json:"cursor,omitempty"`
+	Truncated bool           `json:"truncated,omitempty"`
+}
+
+// ParseDeref maps never, search, find and always to the alias dereferencing policy, empty means never.
+func ParseDeref(deref string) (int, error) {
+	switch strings.ToLower(deref) {
+	case "", "never":
+		return gldap.NeverDerefAliases, nil
+	case "search", "insearching":
+		return gldap.DerefInSearching, nil
+	case "find", "findingbaseobj":
+		return gldap.DerefFindingBaseObj, nil
+	case "always":
+		return gldap.DerefAlways, nil
+	}
+	return 0, fmt.Errorf("unknown deref policy %q, expect one of never, search, find, always", deref)
+}
+
+// SearchPaged runs the search, one page at a time when PageSize is set using
+// the Simple Paged Results control (RFC 2696). The cursor wraps the server
+// cookie, which is only valid on the same connection and for the same search.
+func (op *LDAPOperation) SearchPaged(opts SearchOptions) (*SearchPage, error) {
+	if op.Conn == nil {
+		return nil, gldap.NewError(gldap.LDAPResultUnavailable, errors.New("LDAP connection is not established"))
+	}
+	scope, err := ParseScope(opts.Scope)
+	if err != nil {
+		return nil, err
+	}
+	deref, err := ParseDeref(opts.DerefAliases)
+	if err != nil {
+		return nil, err
+	}
+
+	var controls []gldap.Control
+	if opts.PageSize > 0 {
+		paging := gldap.NewControlPaging(opts.PageSize)
+		if opts.Cursor != "" {
+			cookie, err := base64.RawURLEncoding.DecodeString(opts.Cursor)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			paging.SetCookie(cookie)
+		}
+		controls = append(controls, paging)
+	}
+
+	searchRequest := gldap.NewSearchRequest(
+		opts.BaseDN,
+		scope,
+		deref,
+		opts.SizeLimit, opts.TimeLimit, false,
+		opts.Filter,
+		opts.Attributes,
+		controls,
+	)
+	result, err := op.Conn.Search(searchRequest)
+	truncated := gldap.IsErrorAnyOf(err, gldap.LDAPResultSizeLimitExceeded, gldap.LDAPResultTimeLimitExceeded)
+	if err != nil && !truncated {
+		return nil, err
+	}
+
+	page := &SearchPage{Entries: result.Entries, Truncated: truncated}
+	if control, ok := gldap.FindControl(result.Controls, gldap.ControlTypePaging).(*gldap.ControlPaging); ok && len(control.Cookie) > 0 {
+		page.Cursor = base64.RawURLEncoding.EncodeToString(control.Cookie)
+	}
+	return page, nil
+}
@@ -0,0 +1,206 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+// pagedServer answers search requests like a server with a size limit:
+// without the Simple Paged Results control a result larger than sizeLimit
+// ends with sizeLimitExceeded, with it at most sizeLimit entries are sent per
+// page. The filter is ignored, every entry below the base matches.
+type pagedServer struct {
+	entries   []*gldap.Entry
+	sizeLimit int
+
+	mu    sync.Mutex
+	pages int // pages sent for paged searches
+}
+
+// dial connects a client to the server over a pipe.
+func (s *pagedServer) dial(t *testing.T) *gldap.Conn {
+	client, server := net.Pipe()
+	go s.serve(server)
+	conn := gldap.NewConn(client, false)
+	conn.Start()
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func (s *pagedServer) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		id, _ := packet.Children[0].Value.(int64)
+		request := packet.Children[1]
+		// abandon and unbind get no response
+		if request.ClassType != ber.ClassApplication || request.Tag != gldap.ApplicationSearchRequest {
+			continue
+		}
+		var paging *gldap.ControlPaging
+		if len(packet.Children) > 2 {
+			for _, child := range packet.Children[2].Children {
+				if control, err := gldap.DecodeControl(child); err == nil {
+					if control, ok := control.(*gldap.ControlPaging); ok {
+						paging = control
+					}
+				}
+			}
+		}
+		if err := s.search(conn, id, request.Children[0].Value.(string), paging); err != nil {
+			return
+		}
+	}
+}
+
+func (s *pagedServer) search(conn net.Conn, id int64, base string, paging *gldap.ControlPaging) error {
+	var matches []*gldap.Entry
+	for _, entry := range s.entries {
+		if strings.HasSuffix(strings.ToLower(entry.DN), strings.ToLower(base)) {
+			matches = append(matches, entry)
+		}
+	}
+	if paging == nil {
+		code := gldap.LDAPResultSuccess
+		if len(matches) > s.sizeLimit {
+			matches, code = matches[:s.sizeLimit], gldap.LDAPResultSizeLimitExceeded
+		}
+		for _, entry := range matches {
+			if err := s.send(conn, id, encodeEntry(entry), nil); err != nil {
+				return err
+			}
+		}
+		return s.send(conn, id, encodeDone(code), nil)
+	}
+
+	// a page size of 0 abandons the paged search
+	if paging.PagingSize == 0 {
+		return s.send(conn, id, encodeDone(gldap.LDAPResultSuccess), gldap.NewControlPaging(0))
+	}
+	offset, _ := strconv.Atoi(string(paging.Cookie))
+	end := min(offset+int(paging.PagingSize), offset+s.sizeLimit, len(matches))
+	for _, entry := range matches[offset:end] {
+		if err := s.send(conn, id, encodeEntry(entry), nil); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	s.pages++
+	s.mu.Unlock()
+	next := gldap.NewControlPaging(0)
+	if end < len(matches) {
+		next.SetCookie([]byte(strconv.Itoa(end)))
+	}
+	return s.send(conn, id, encodeDone(gldap.LDAPResultSuccess), next)
+}
+
+func (s *pagedServer) send(conn net.Conn, id int64, op *ber.Packet, control gldap.Control) error {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "MessageID"))
+	packet.AppendChild(op)
+	if control != nil {
+		controls := ber.Encode(ber.ClassContext, ber.TypeConstructed, 0, nil, "Controls")
+		controls.AppendChild(control.Encode())
+		packet.AppendChild(controls)
+	}
+	_, err := conn.Write(packet.Bytes())
+	return err
+}
+
+func encodeEntry(entry *gldap.Entry) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, gldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "DN"))
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for _, attr := range entry.Attributes {
+		item := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		item.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, attr.Name, "Type"))
+		values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range attr.Values {
+			values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		item.AppendChild(values)
+		attributes.AppendChild(item)
+	}
+	packet.AppendChild(attributes)
+	return packet
+}
+
+func encodeDone(code int) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, gldap.ApplicationSearchResultDone, nil, "Search Result Done")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return packet
+}
+
+// largeSubtree returns an ou with count people below it.
+func largeSubtree(count int) []*gldap.Entry {
+	entries := []*gldap.Entry{gldap.NewEntry("ou=people,dc=example,dc=com", map[string][]string{"objectClass": {"organizationalUnit"}, "ou": {"people"}})}
+	for i := 0; i < count; i++ {
+		uid := "user" + strconv.Itoa(i)
+		entries = append(entries, gldap.NewEntry("uid="+uid+",ou=people,dc=example,dc=com",
+			map[string][]string{"objectClass": {"inetOrgPerson"}, "uid": {uid}, "cn": {uid}, "sn": {uid}}))
+	}
+	return entries
+}
+
+func TestDeleteTreeDryRunPaged(t *testing.T) {
+	server := &pagedServer{entries: largeSubtree(7), sizeLimit: 3}
+	op := &LDAPOperation{Conn: server.dial(t)}
+
+	dns, err := op.DeleteTree("ou=people,dc=example,dc=com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dns) != 8 || dns[len(dns)-1] != "ou=people,dc=example,dc=com" {
+		t.Errorf("expect the 7 children before the ou, get %v", dns)
+	}
+	if !slices.Contains(dns, "uid=user6,ou=people,dc=example,dc=com") {
+		t.Errorf("expect the entries of the last page, get %v", dns)
+	}
+	if server.pages != 3 {
+		t.Errorf("expect 3 pages, get %d", server.pages)
+	}
+}
+
+func TestStreamSearchPaged(t *testing.T) {
+	server := &pagedServer{entries: largeSubtree(7), sizeLimit: 3}
+	op := &LDAPOperation{Conn: server.dial(t)}
+
+	var dns []string
+	err := op.StreamSearch(context.Background(), "ou=people,dc=example,dc=com", "(objectClass=*)", gldap.ScopeWholeSubtree, func(entry *gldap.Entry) error {
+		dns = append(dns, entry.DN)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dns) != 8 || server.pages != 3 {
+		t.Errorf("expect 8 entries in 3 pages, get %d entries in %d pages", len(dns), server.pages)
+	}
+
+	// an error of fn stops the search
+	stop := errors.New("stop")
+	count := 0
+	err = op.StreamSearch(context.Background(), "ou=people,dc=example,dc=com", "(objectClass=*)", gldap.ScopeWholeSubtree, func(entry *gldap.Entry) error {
+		if count++; count == 5 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || count != 5 {
+		t.Errorf("expect the search to stop at the 5th entry, get %v after %d", err, count)
+	}
+}
@@ -0,0 +1,255 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	gldap "github.com/go-ldap/ldap/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// EntryTemplate describes how to create a common kind of entry, e.g.
+//
+//	name: user
+//	objectClasses: [inetOrgPerson]
+//	rdn: uid
+//	parent: ou=person,{base}
+//	required: [uid, givenName, sn]
+//	defaults:
+//	  loginShell: [/bin/bash]
+//	derived:
+//	  cn: "{givenName} {sn}"
+//	  homeDirectory: /home/{uid}
+//
+// Derived values and defaults may use {attribute} placeholders, replaced by
+// the first value of the attribute, and {base} for the naming context.
+type EntryTemplate struct {
+	Name          string              `json:"name" yaml:"name"`
+	Description   string              `json:"description" yaml:"description"`
+	ObjectClasses []string            `json:"objectClasses" yaml:"objectClasses"`
+	RDN           string              `json:"rdn" yaml:"rdn"`       // attribute naming the entry
+	Parent        string              `json:"parent" yaml:"parent"` // DN the entry is created under
+	Required      []string            `json:"required" yaml:"required"`
+	Defaults      map[string][]string `json:"defaults" yaml:"defaults"` // used when the input misses the attribute
+	Derived       map[string]string   `json:"derived" yaml:"derived"`   // computed from other attributes
+}
+
+// RenderedEntry is the add request a template produces.
+type RenderedEntry struct {
+	DN         string              `json:"dn"`
+	Attributes map[string][]string `json:"attributes"`
+}
+
+var placeholderPattern = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// LoadTemplates reads the *.yaml, *.yml and *.json templates of dir, a
+// template without a name is named after its file.
+func LoadTemplates(dir string) (map[string]*EntryTemplate, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	templates := make(map[string]*EntryTemplate)
+	for _, file := range files {
+		ext := strings.ToLower(filepath.Ext(file.Name()))
+		if file.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		// JSON is valid YAML, one decoder reads both
+		tmpl := &EntryTemplate{}
+		if err := yaml.Unmarshal(content, tmpl); err != nil {
+			return nil, fmt.Errorf("template %s: %w", file.Name(), err)
+		}
+		if tmpl.Name == "" {
+			tmpl.Name = strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
+		}
+		if err := tmpl.Validate(); err != nil {
+			return nil, fmt.Errorf("template %s: %w", file.Name(), err)
+		}
+		if _, exist := templates[tmpl.Name]; exist {
+			return nil, fmt.Errorf("template %s is defined twice", tmpl.Name)
+		}
+		templates[tmpl.Name] = tmpl
+	}
+	return templates, nil
+}
+
+// SortedTemplates lists templates by name.
+func SortedTemplates(templates map[string]*EntryTemplate) []*EntryTemplate {
+	result := make([]*EntryTemplate, 0, len(templates))
+	for _, tmpl := range templates {
+		result = append(result, tmpl)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Validate checks the template is complete.
+func (t *EntryTemplate) Validate() error {
+	if len(t.ObjectClasses) == 0 {
+		return errors.New("objectClasses is required")
+	}
+	if t.RDN == "" {
+		return errors.New("rdn is required")
+	}
+	for name, expr := range t.Derived {
+		for _, ref := range placeholders(expr) {
+			if strings.EqualFold(ref, name) {
+				return fmt.Errorf("derived %s refers to itself", name)
+			}
+		}
+	}
+	return nil
+}
+
+// Render builds the add request from the input attributes. The input wins
+// over derived values, derived values win over defaults. parent overrides
+// the parent of the template when given.
+func (t *EntryTemplate) Render(input map[string][]string, parent, base string) (*RenderedEntry, error) {
+	attrs := make(map[string][]string)
+	set := func(name string, values []string) {
+		for key := range attrs {
+			if strings.EqualFold(key, name) {
+				attrs[key] = values
+				return
+			}
+		}
+		attrs[name] = values
+	}
+	lookup := func(name string) ([]string, bool) {
+		for key, values := range attrs {
+			if strings.EqualFold(key, name) && len(values) > 0 && values[0] != "" {
+				return values, true
+			}
+		}
+		return nil, false
+	}
+
+	for name, values := range input {
+		if strings.EqualFold(name, "objectClass") {
+			continue
+		}
+		if len(values) > 0 {
+			set(name, values)
+		}
+	}
+	var missing []string
+	for _, name := range t.Required {
+		if _, exist := lookup(name); !exist {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("template %s requires %s", t.Name, strings.Join(missing, ", "))
+	}
+
+	// derived values may build on each other, resolve until nothing changes
+	pending := make(map[string]string)
+	for name, expr := range t.Derived {
+		if _, exist := lookup(name); !exist {
+			pending[name] = expr
+		}
+	}
+	derive := func() {
+		for len(pending) > 0 {
+			progress := false
+			for name, expr := range pending {
+				value, ok := t.expand(expr, base, lookup)
+				if !ok {
+					continue
+				}
+				set(name, []string{value})
+				delete(pending, name)
+				progress = true
+			}
+			if !progress {
+				return
+			}
+		}
+	}
+	derive()
+
+	for name, values := range t.Defaults {
+		if _, exist := lookup(name); exist {
+			continue
+		}
+		expanded := make([]string, 0, len(values))
+		for _, value := range values {
+			// a default whose placeholders can not be filled is left out
+			if value, ok := t.expand(value, base, lookup); ok {
+				expanded = append(expanded, value)
+			}
+		}
+		if len(expanded) > 0 {
+			set(name, expanded)
+		}
+	}
+	// a derived value may build on a default, it still wins over the default
+	derive()
+	if len(pending) > 0 {
+		names := make([]string, 0, len(pending))
+		for name := range pending {
+			names = append(names, fmt.Sprintf("%s needs %s", name, strings.Join(placeholders(pending[name]), ", ")))
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("template %s can not derive %s", t.Name, strings.Join(names, "; "))
+	}
+
+	rdnValues, exist := lookup(t.RDN)
+	if !exist {
+		return nil, fmt.Errorf("template %s: value of the rdn attribute %s is missing", t.Name, t.RDN)
+	}
+	if parent == "" {
+		parent = strings.ReplaceAll(t.Parent, "{base}", base)
+	}
+	if parent == "" {
+		parent = base
+	}
+	if _, err := gldap.ParseDN(parent); err != nil {
+		return nil, fmt.Errorf("template %s: invalid parent %s: %w", t.Name, parent, err)
+	}
+	attrs["objectClass"] = append([]string(nil), t.ObjectClasses...)
+	return &RenderedEntry{
+		DN:         JoinDN(t.RDN+"="+gldap.EscapeDN(rdnValues[0]), parent),
+		Attributes: attrs,
+	}, nil
+}
+
+// expand replaces the placeholders of expr, ok is false when one of the
+// referenced attributes has no value yet.
+func (t *EntryTemplate) expand(expr, base string, lookup func(string) ([]string, bool)) (string, bool) {
+	ok := true
+	value := placeholderPattern.ReplaceAllStringFunc(expr, func(match string) string {
+		name := match[1 : len(match)-1]
+		if name == "base" {
+			return base
+		}
+		values, exist := lookup(name)
+		if !exist {
+			ok = false
+			return match
+		}
+		return values[0]
+	})
+	return value, ok
+}
+
+// placeholders lists the attributes referenced by expr.
+func placeholders(expr string) []string {
+	var names []string
+	for _, match := range placeholderPattern.FindAllStringSubmatch(expr, -1) {
+		if match[1] != "base" {
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
@@ -0,0 +1,113 @@
+package ldap
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	tmpl := &EntryTemplate{
+		Name:          "posix-user",
+		ObjectClasses: []string{"inetOrgPerson", "posixAccount"},
+		RDN:           "uid",
+		Parent:        "ou=person,{base}",
+		Required:      []string{"uid", "givenName", "sn"},
+		Defaults:      map[string][]string{"loginShell": {"/bin/bash"}, "gidNumber": {"100"}, "mail": {"{uid}@{domain}"}},
+		Derived: map[string]string{
+			"cn":            "{givenName} {sn}",
+			"displayName":   "{cn}",
+			"homeDirectory": "/home/{uid}",
+			"gecos":         "{cn},{gidNumber}",
+		},
+	}
+	if err := tmpl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := tmpl.Render(map[string][]string{
+		"uid": {"jdoe"}, "givenName": {"John"}, "sn": {"Doe"}, "loginShell": {"/bin/zsh"}, "objectClass": {"device"},
+	}, "", "dc=example,dc=com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.DN != "uid=jdoe,ou=person,dc=example,dc=com" {
+		t.Errorf("unexpected dn %s", entry.DN)
+	}
+	expect := map[string]string{
+		"cn": "John Doe", "displayName": "John Doe", "homeDirectory": "/home/jdoe",
+		"loginShell": "/bin/zsh", "gidNumber": "100", "gecos": "John Doe,100",
+		"objectClass": "inetOrgPerson,posixAccount",
+	}
+	for name, value := range expect {
+		if got := strings.Join(entry.Attributes[name], ","); got != value {
+			t.Errorf("%s: get %q, expect %q", name, got, value)
+		}
+	}
+	if _, exist := entry.Attributes["mail"]; exist {
+		t.Errorf("default with an unknown placeholder must be left out, get %v", entry.Attributes["mail"])
+	}
+
+	// the input wins over derived values and the parent may be overridden
+	entry, err = tmpl.Render(map[string][]string{
+		"uid": {"a,b"}, "givenName": {"A"}, "sn": {"B"}, "cn": {"Custom"},
+	}, "ou=guests,dc=example,dc=com", "dc=example,dc=com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.DN != `uid=a\,b,ou=guests,dc=example,dc=com` || entry.Attributes["displayName"][0] != "Custom" {
+		t.Errorf("unexpected entry %v", entry)
+	}
+
+	if _, err := tmpl.Render(map[string][]string{"uid": {"jdoe"}}, "", "dc=example,dc=com"); err == nil ||
+		!strings.Contains(err.Error(), "givenName, sn") {
+		t.Errorf("expect the missing required attributes, get %v", err)
+	}
+
+	broken := &EntryTemplate{Name: "broken", ObjectClasses: []string{"person"}, RDN: "cn",
+		Derived: map[string]string{"cn": "{givenName}"}}
+	if _, err := broken.Render(map[string][]string{"sn": {"Doe"}}, "", "dc=example,dc=com"); err == nil ||
+		!strings.Contains(err.Error(), "cn needs givenName") {
+		t.Errorf("expect a derive error, get %v", err)
+	}
+	for _, invalid := range []*EntryTemplate{
+		{Name: "no classes", RDN: "cn"},
+		{Name: "no rdn", ObjectClasses: []string{"person"}},
+		{Name: "self", ObjectClasses: []string{"person"}, RDN: "cn", Derived: map[string]string{"cn": "{CN}"}},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("%s: expect an error", invalid.Name)
+		}
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	templates, err := LoadTemplates("../templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tmpl := range SortedTemplates(templates) {
+		names = append(names, tmpl.Name)
+	}
+	if expect := []string{"group", "organizational-unit", "posix-user", "user"}; !slices.Equal(names, expect) {
+		t.Errorf("get templates %v, expect %v", names, expect)
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "group.yml"), []byte("objectClasses: [groupOfNames]\nrdn: cn\n"), 0o600)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a template"), 0o600)
+	templates, err = LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 1 || templates["group"] == nil {
+		t.Errorf("expect the template named after its file, get %v", templates)
+	}
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"name": "broken", "rdn": "cn"}`), 0o600)
+	if _, err := LoadTemplates(dir); err == nil {
+		t.Error("expect an error for a template without objectClasses")
+	}
+}
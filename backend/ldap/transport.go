@@ -0,0 +1,94 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// transport modes used to reach the directory server
+const (
+	TransportPlain    = "plain"
+	TransportStartTLS = "starttls"
+	TransportLDAPS    = "ldaps"
+)
+
+// TransportOptions describes how the connection to the server is secured.
+type TransportOptions struct {
+	Mode               string `json:"mode"`
+	CAFile             string `json:"caFile"`
+	CAPem              string `json:"-"` // PEM encoded CA bundle, used in addition to CAFile
+	CertFile           string `json:"certFile"`
+	KeyFile            string `json:"keyFile"`
+	ServerName         string `json:"serverName"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"`
+}
+
+// ParseTransportMode normalizes a user supplied transport mode, empty means plain.
+func ParseTransportMode(mode string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", TransportPlain, "ldap":
+		return TransportPlain, nil
+	case TransportStartTLS, "start-tls", "tls":
+		return TransportStartTLS, nil
+	case TransportLDAPS, "ssl":
+		return TransportLDAPS, nil
+	}
+	return "", fmt.Errorf("unknown transport mode %q, expect one of plain, starttls, ldaps", mode)
+}
+
+// Scheme returns the url scheme to dial for the configured mode.
+func (t *TransportOptions) Scheme() string {
+	if t.Mode == TransportLDAPS {
+		return "ldaps"
+	}
+	return "ldap"
+}
+
+// TLSConfig builds the tls configuration for StartTLS and LDAPS, host is used
+// as server name when no override is given.
+func (t *TransportOptions) TLSConfig(host string) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if t.ServerName != "" {
+		config.ServerName = t.ServerName
+	}
+
+	if t.CAFile != "" || t.CAPem != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if t.CAFile != "" {
+			pem, err := os.ReadFile(t.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("read CA bundle: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificate found in CA bundle %s", t.CAFile)
+			}
+		}
+		if t.CAPem != "" && !pool.AppendCertsFromPEM([]byte(t.CAPem)) {
+			return nil, errors.New("no certificate found in the given CA bundle")
+		}
+		config.RootCAs = pool
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		if t.CertFile == "" || t.KeyFile == "" {
+			return nil, errors.New("client certificate and key must be given together")
+		}
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
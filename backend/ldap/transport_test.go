@@ -0,0 +1,50 @@
+package ldap
+
+import "testing"
+
+func TestParseTransportMode(t *testing.T) {
+	values := []struct {
+		input  string
+		expect string
+	}{
+		{"", TransportPlain},
+		{"plain", TransportPlain},
+		{"StartTLS", TransportStartTLS},
+		{"ldaps", TransportLDAPS},
+	}
+	for _, value := range values {
+		mode, err := ParseTransportMode(value.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode != value.expect {
+			t.Errorf("get value: %s, expect: %s", mode, value.expect)
+		}
+	}
+	if _, err := ParseTransportMode("smtp"); err == nil {
+		t.Error("expect error for unknown transport mode")
+	}
+}
+
+func TestTransportTLSConfig(t *testing.T) {
+	transport := TransportOptions{Mode: TransportLDAPS, ServerName: "ldap.internal", InsecureSkipVerify: true}
+	config, err := transport.TLSConfig("192.168.20.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.ServerName != "ldap.internal" || !config.InsecureSkipVerify {
+		t.Errorf("unexpected tls config: %s %v", config.ServerName, config.InsecureSkipVerify)
+	}
+	if transport.Scheme() != "ldaps" {
+		t.Errorf("get scheme: %s, expect: ldaps", transport.Scheme())
+	}
+
+	transport = TransportOptions{Mode: TransportStartTLS, CAPem: "not a certificate"}
+	if _, err := transport.TLSConfig("localhost"); err == nil {
+		t.Error("expect error for invalid CA bundle")
+	}
+	transport = TransportOptions{Mode: TransportStartTLS, CertFile: "client.pem"}
+	if _, err := transport.TLSConfig("localhost"); err == nil {
+		t.Error("expect error for certificate without key")
+	}
+}
@@ -0,0 +1,456 @@
+package ldap
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	gldap "github.com/go-ldap/ldap/v3"
+)
+
+// syntax oids checked by the validator
+const (
+	SyntaxBoolean         = "1.3.6.1.4.1.1466.115.121.1.7"
+	SyntaxDN              = "1.3.6.1.4.1.1466.115.121.1.12"
+	SyntaxGeneralizedTime = "1.3.6.1.4.1.1466.115.121.1.24"
+	SyntaxInteger         = "1.3.6.1.4.1.1466.115.121.1.27"
+)
+
+// ValidationError is one problem of an entry, Attribute is empty for
+// problems of the entry as a whole.
+type ValidationError struct {
+	Attribute string `json:"attribute"`
+	Message   string `json:"message"`
+}
+
+// ValidationErrors is returned by AddEntry and ModifyRecord when the entry
+// violates the schema.
+type ValidationErrors []ValidationError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(v))
+	for _, item := range v {
+		if item.Attribute == "" {
+			msgs = append(msgs, item.Message)
+		} else {
+			msgs = append(msgs, item.Attribute+": "+item.Message)
+		}
+	}
+	return "schema violation: " + strings.Join(msgs, "; ")
+}
+
+// Loaded reports whether a schema has been read, validation is skipped without it.
+func (p *ObjectClassParser) Loaded() bool {
+	return len(p.Objects) > 0
+}
+
+// ValidateEntry checks the attributes of a complete entry against the schema:
+// one structural chain, the MUST attributes not filled in by the server present, nothing outside MUST and
+// MAY unless extensibleObject is used, single-valued attributes and the value
+// syntax of integers, booleans, DNs and generalized times.
+func (p *ObjectClassParser) ValidateEntry(attrs map[string][]string) ValidationErrors {
+	errs, classified := p.classProblems(attrs)
+	if !classified {
+		return errs
+	}
+	add := func(attr, format string, args ...any) {
+		errs = append(errs, ValidationError{Attribute: attr, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for name, values := range attrs {
+		attrType, known := p.EffectiveAttributeType(attributeBase(name))
+		if len(p.AttributeTypes) > 0 && !known {
+			add(name, "unknown attribute")
+			continue
+		}
+		if len(values) == 0 {
+			add(name, "attribute has no value")
+		}
+		if !known {
+			continue
+		}
+		if attrType.NoUserModification {
+			add(name, "attribute can not be modified by users")
+		}
+		if attrType.SingleValue && len(values) > 1 {
+			add(name, "attribute is single-valued, %d values given", len(values))
+		}
+		for _, value := range values {
+			if err := checkSyntax(attrType.Syntax, value); err != "" {
+				add(name, "invalid value %q: %s", value, err)
+			}
+		}
+	}
+	return errs
+}
+
+// classProblems checks attrs against their objectClasses: known classes in
+// one structural chain, the MUSTs not filled in by the server present and
+// nothing outside MUST and MAY unless extensibleObject is used. classified is
+// false when attrs has no usable objectClass, the other checks are skipped then.
+func (p *ObjectClassParser) classProblems(attrs map[string][]string) (errs ValidationErrors, classified bool) {
+	add := func(attr, format string, args ...any) {
+		errs = append(errs, ValidationError{Attribute: attr, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var classes []*ObjectClass
+	extensible := false
+	for name, values := range attrs {
+		if !strings.EqualFold(attributeBase(name), "objectClass") {
+			continue
+		}
+		for _, value := range values {
+			obj, exist := p.objectClass(value)
+			if !exist {
+				add(name, "unknown objectClass %s", value)
+				continue
+			}
+			if _, err := p.GetInheritenceChain(obj.Name[0]); err != nil {
+				add(name, "%v", err)
+				continue
+			}
+			if strings.EqualFold(obj.Name[0], "extensibleObject") {
+				extensible = true
+			}
+			classes = append(classes, obj)
+		}
+	}
+	if len(classes) == 0 {
+		if len(errs) == 0 {
+			add("objectClass", "objectClass is required")
+		}
+		return errs, false
+	}
+	if err := p.checkStructuralChain(classes); err != "" {
+		add("objectClass", "%s", err)
+	}
+
+	// collect MUST and MAY of all classes keyed by the attribute identity
+	must := map[string]string{}
+	allowed := map[string]bool{}
+	for _, obj := range classes {
+		// the hierarchy of classes is checked above
+		musts, mays, _ := p.GetAllAttributees(obj.Name[0])
+		for _, attr := range musts {
+			must[p.attributeKey(attr)] = attr
+			allowed[p.attributeKey(attr)] = true
+		}
+		for _, attr := range mays {
+			allowed[p.attributeKey(attr)] = true
+		}
+	}
+	present := map[string]bool{}
+	for name := range attrs {
+		present[p.attributeKey(attributeBase(name))] = true
+	}
+	for key, attr := range must {
+		if !present[key] && !p.serverPopulated(attr) {
+			add(attr, "attribute is required")
+		}
+	}
+
+	for name := range attrs {
+		base := attributeBase(name)
+		attrType, known := p.EffectiveAttributeType(base)
+		// unknown attributes are reported by the attribute checks
+		if len(p.AttributeTypes) > 0 && !known {
+			continue
+		}
+		if !allowed[p.attributeKey(base)] && !extensible && (!known || attrType.Usage == UserApplications) {
+			add(name, "attribute is not allowed by the objectClasses")
+		}
+	}
+	return errs, true
+}
+
+// ValidateModify checks the changes applied to the stored attrs. The changed
+// attributes are checked for user modification, unknown attributes, single
+// values and the syntax of the new values. The objectClass checks of
+// ValidateEntry run on the result, but only problems of changed attributes
+// are reported, and after an objectClass change the problems it introduced.
+// An entry that already breaks the schema, e.g. one read with the operational
+// attributes of Active Directory, can still be modified.
+func (p *ObjectClassParser) ValidateModify(attrs map[string][]string, changes []AttributeChange) ValidationErrors {
+	// apply the changes to the stored name of an attribute, e.g. surname to sn
+	stored := make([]AttributeChange, len(changes))
+	for i, change := range changes {
+		stored[i] = change
+		key := p.attributeKey(change.Attribute)
+		for name := range attrs {
+			if p.attributeKey(name) == key {
+				stored[i].Attribute = name
+				break
+			}
+		}
+	}
+	result := ApplyChanges(attrs, stored)
+	errs := p.changeProblems(result, changes)
+	classChanged := slices.ContainsFunc(changes, func(change AttributeChange) bool {
+		return strings.EqualFold(attributeBase(change.Attribute), "objectClass")
+	})
+
+	changed := func(attr string) bool {
+		key := p.attributeKey(attributeBase(attr))
+		return slices.ContainsFunc(changes, func(change AttributeChange) bool {
+			return p.attributeKey(attributeBase(change.Attribute)) == key
+		})
+	}
+	problems, _ := p.classProblems(result)
+	var before ValidationErrors
+	if classChanged {
+		before, _ = p.classProblems(attrs)
+	}
+	for _, problem := range problems {
+		switch {
+		case changed(problem.Attribute):
+		case classChanged && !slices.Contains(before, problem):
+		default:
+			continue
+		}
+		errs = append(errs, problem)
+	}
+	return errs
+}
+
+// changeProblems checks the attributes named in changes for user
+// modification, unknown attributes, single values and the syntax of the new
+// values, result is the entry with the changes applied.
+func (p *ObjectClassParser) changeProblems(result map[string][]string, changes []AttributeChange) ValidationErrors {
+	var errs ValidationErrors
+	add := func(attr, format string, args ...any) {
+		errs = append(errs, ValidationError{Attribute: attr, Message: fmt.Sprintf(format, args...)})
+	}
+
+	checked := map[string]bool{}
+	for _, change := range changes {
+		name := change.Attribute
+		attrType, known := p.EffectiveAttributeType(attributeBase(name))
+		if len(p.AttributeTypes) > 0 && !known {
+			add(name, "unknown attribute")
+			continue
+		}
+		if !known {
+			continue
+		}
+		key := p.attributeKey(attributeBase(name))
+		if !checked[key] {
+			checked[key] = true
+			if attrType.NoUserModification {
+				add(name, "attribute can not be modified by users")
+			}
+			for resultName, values := range result {
+				if strings.EqualFold(resultName, name) && attrType.SingleValue && len(values) > 1 {
+					add(name, "attribute is single-valued, %d values given", len(values))
+				}
+			}
+		}
+		if strings.EqualFold(change.Operation, ModifyDelete) {
+			continue
+		}
+		for _, value := range change.Values {
+			if err := checkSyntax(attrType.Syntax, value); err != "" {
+				add(name, "invalid value %q: %s", value, err)
+			}
+		}
+	}
+
+	return errs
+}
+
+// serverPopulated reports whether the server fills attr in itself, so a new
+// entry may leave it out although it is a MUST: operational and
+// NO-USER-MODIFICATION attributes, and the MUSTs of top besides objectClass,
+// e.g. instanceType, nTSecurityDescriptor and objectCategory of Active Directory.
+func (p *ObjectClassParser) serverPopulated(attr string) bool {
+	if attrType, known := p.EffectiveAttributeType(attr); known &&
+		(attrType.NoUserModification || attrType.Usage != UserApplications) {
+		return true
+	}
+	if strings.EqualFold(attr, "objectClass") {
+		return false
+	}
+	top, exist := p.objectClass("top")
+	if !exist {
+		return false
+	}
+	key := p.attributeKey(attr)
+	for _, must := range top.Must {
+		if p.attributeKey(must) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// checkStructuralChain makes sure all structural classes belong to a single
+// superclass chain, returns the problem or "".
+func (p *ObjectClassParser) checkStructuralChain(classes []*ObjectClass) string {
+	var structurals []*ObjectClass
+	for _, obj := range classes {
+		if obj.Type == STRUCTURAL {
+			structurals = append(structurals, obj)
+		}
+	}
+	if len(structurals) == 0 {
+		return "a structural objectClass is required"
+	}
+	// the most specific class has every other structural class in its chain
+	for _, candidate := range structurals {
+		chain := map[string]bool{}
+		names, _ := p.GetInheritenceChain(candidate.Name[0])
+		for _, name := range names {
+			chain[strings.ToLower(name)] = true
+		}
+		covered := true
+		for _, obj := range structurals {
+			if !chain[strings.ToLower(obj.Name[0])] {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return ""
+		}
+	}
+	names := make([]string, 0, len(structurals))
+	for _, obj := range structurals {
+		names = append(names, obj.Name[0])
+	}
+	return fmt.Sprintf("structural objectClasses %s do not form a single chain", strings.Join(names, ", "))
+}
+
+// objectClass looks an object class up by name or oid, ignoring case.
+func (p *ObjectClassParser) objectClass(name string) (*ObjectClass, bool) {
+	if obj, exist := p.Objects[name]; exist {
+		return obj, true
+	}
+	for key, obj := range p.Objects {
+		if strings.EqualFold(key, name) || obj.Oid == name {
+			return obj, true
+		}
+	}
+	return nil, false
+}
+
+// attributeKey identifies an attribute independent of the alias used, e.g. cn and commonName.
+func (p *ObjectClassParser) attributeKey(name string) string {
+	if attr, exist := p.AttributeType(name); exist {
+		return attr.Oid
+	}
+	return strings.ToLower(name)
+}
+
+// attributeBase strips the options of an attribute description, e.g. cn;lang-en.
+func attributeBase(name string) string {
+	base, _, _ := strings.Cut(name, ";")
+	return base
+}
+
+var integerPattern = regexp.MustCompile(`^(0|-?[1-9][0-9]*)$`)
+
+// generalized time layouts, minutes and seconds are optional and a fraction
+// after the seconds is accepted by time.Parse
+var generalizedTimeLayouts = []string{
+	"20060102150405Z0700", "200601021504Z0700", "2006010215Z0700",
+	"20060102150405Z07", "200601021504Z07", "2006010215Z07",
+}
+
+// checkSyntax checks value against a few common syntaxes, returns the problem or "".
+func checkSyntax(syntax, value string) string {
+	switch syntax {
+	case SyntaxInteger:
+		if !integerPattern.MatchString(value) {
+			return "not an integer"
+		}
+	case SyntaxBoolean:
+		if value != "TRUE" && value != "FALSE" {
+			return "boolean must be TRUE or FALSE"
+		}
+	case SyntaxDN:
+		if _, err := gldap.ParseDN(value); err != nil {
+			return "not a valid DN"
+		}
+	case SyntaxGeneralizedTime:
+		if _, err := ParseGeneralizedTime(value); err != nil {
+			return "not a generalized time"
+		}
+	}
+	return ""
+}
+
+// ParseGeneralizedTime parses the generalized time syntax, e.g. 20240131235959Z.
+func ParseGeneralizedTime(value string) (time.Time, error) {
+	// a comma may separate the fraction
+	normalized := strings.Replace(value, ",", ".", 1)
+	for _, layout := range generalizedTimeLayouts {
+		if t, err := time.Parse(layout, normalized); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid generalized time %s", value)
+}
+
+// EntryAttributes returns the attributes of entry as a map of raw values.
+func EntryAttributes(entry *gldap.Entry) map[string][]string {
+	attrs := make(map[string][]string, len(entry.Attributes))
+	for _, attr := range entry.Attributes {
+		values := make([]string, 0, len(attr.ByteValues))
+		for _, value := range attr.ByteValues {
+			values = append(values, string(value))
+		}
+		if len(attr.ByteValues) == 0 {
+			values = append(values, attr.Values...)
+		}
+		attrs[attr.Name] = values
+	}
+	return attrs
+}
+
+// ApplyChanges returns a copy of attrs with the changes applied the way the
+// server would, used to validate a modify before it is sent.
+func ApplyChanges(attrs map[string][]string, changes []AttributeChange) map[string][]string {
+	result := make(map[string][]string, len(attrs))
+	for name, values := range attrs {
+		result[name] = append([]string(nil), values...)
+	}
+	find := func(name string) string {
+		for key := range result {
+			if strings.EqualFold(key, name) {
+				return key
+			}
+		}
+		return name
+	}
+	for _, change := range changes {
+		key := find(change.Attribute)
+		switch strings.ToLower(change.Operation) {
+		case ModifyAdd:
+			result[key] = append(result[key], change.Values...)
+		case ModifyReplace:
+			if len(change.Values) == 0 {
+				delete(result, key)
+			} else {
+				result[key] = append([]string(nil), change.Values...)
+			}
+		case ModifyDelete:
+			if len(change.Values) == 0 {
+				delete(result, key)
+				continue
+			}
+			var kept []string
+			for _, value := range result[key] {
+				if !slices.Contains(change.Values, value) {
+					kept = append(kept, value)
+				}
+			}
+			if len(kept) == 0 {
+				delete(result, key)
+			} else {
+				result[key] = kept
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,309 @@
+package ldap
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func validatorSchema(t *testing.T) *ObjectClassParser {
+	parser := NewObjectClassParser()
+	attributes := []string{
+		"( 2.5.4.0 NAME 'objectClass' EQUALITY objectIdentifierMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.38 )",
+		"( 2.5.4.41 NAME 'name' EQUALITY caseIgnoreMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15{32768} )",
+		"( 2.5.4.3 NAME ( 'cn' 'commonName' ) SUP name )",
+		"( 2.5.4.4 NAME ( 'sn' 'surname' ) SUP name )",
+		"( 2.5.4.12 NAME 'title' SUP name )",
+		"( 2.5.4.13 NAME 'description' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )",
+		"( 0.9.2342.19200300.100.1.1 NAME ( 'uid' 'userid' ) SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )",
+		"( 0.9.2342.19200300.100.1.3 NAME ( 'mail' 'rfc822Mailbox' ) SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 )",
+		"( 2.5.4.34 NAME 'seeAlso' SUP distinguishedName )",
+		"( 2.5.4.49 NAME 'distinguishedName' SYNTAX 1.3.6.1.4.1.1466.115.121.1.12 )",
+		"( 2.16.840.1.113730.3.1.241 NAME 'displayName' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 SINGLE-VALUE )",
+		"( 1.3.6.1.1.1.1.0 NAME 'uidNumber' SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 SINGLE-VALUE )",
+		"( 1.3.6.1.1.1.1.1 NAME 'gidNumber' SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 SINGLE-VALUE )",
+		"( 1.3.6.1.1.1.1.3 NAME 'homeDirectory' SYNTAX 1.3.6.1.4.1.1466.115.121.1.26 SINGLE-VALUE )",
+		"( 1.3.6.1.4.1.42.2.27.8.1.17 NAME 'pwdReset' SYNTAX 1.3.6.1.4.1.1466.115.121.1.7 SINGLE-VALUE )",
+		"( 1.3.6.1.4.1.42.2.27.8.1.16 NAME 'pwdChangedTime' SYNTAX 1.3.6.1.4.1.1466.115.121.1.24 SINGLE-VALUE )",
+		"( 2.5.18.1 NAME 'createTimestamp' SYNTAX 1.3.6.1.4.1.1466.115.121.1.24 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )",
+	}
+	classes := []string{
+		"( 2.5.6.0 NAME 'top' ABSTRACT MUST objectClass )",
+		"( 2.5.6.6 NAME 'person' SUP top STRUCTURAL MUST ( sn $ cn ) MAY ( description $ seeAlso ) )",
+		"( 2.5.6.7 NAME 'organizationalPerson' SUP person STRUCTURAL MAY title )",
+		"( 2.16.840.1.113730.3.2.2 NAME 'inetOrgPerson' SUP organizationalPerson STRUCTURAL MAY ( uid $ mail $ displayName ) )",
+		"( 2.5.6.11 NAME 'applicationProcess' SUP top STRUCTURAL MUST cn MAY description )",
+		"( 1.3.6.1.1.1.2.0 NAME 'posixAccount' SUP top AUXILIARY MUST ( cn $ uid $ uidNumber $ gidNumber $ homeDirectory ) )",
+		"( 1.3.6.1.4.1.42.2.27.8.2.1 NAME 'pwdPolicyTest' SUP top AUXILIARY MAY ( pwdReset $ pwdChangedTime ) )",
+		"( 1.3.6.1.4.1.1466.101.120.111 NAME 'extensibleObject' SUP top AUXILIARY )",
+	}
+	for _, attr := range attributes {
+		if _, err := parser.ParseAttributeType(attr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, class := range classes {
+		if _, err := parser.ParseObjectClass(class); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return parser
+}
+
+func TestValidateEntry(t *testing.T) {
+	parser := validatorSchema(t)
+	values := []struct {
+		name   string
+		attrs  map[string][]string
+		expect []string // attributes with a problem, "" for the entry itself
+	}{
+		{
+			name:  "valid inetOrgPerson",
+			attrs: map[string][]string{"objectClass": {"top", "person", "organizationalPerson", "inetOrgPerson"}, "cn": {"John"}, "sn": {"Doe"}, "mail": {"john@example.com"}},
+		},
+		{
+			name:  "alias and case of names",
+			attrs: map[string][]string{"objectclass": {"InetOrgPerson"}, "commonName": {"John"}, "surname": {"Doe"}, "cn;lang-en": {"John"}},
+		},
+		{
+			name:   "missing must",
+			attrs:  map[string][]string{"objectClass": {"person"}, "cn": {"John"}},
+			expect: []string{"sn"},
+		},
+		{
+			name:   "missing must of auxiliary class",
+			attrs:  map[string][]string{"objectClass": {"inetOrgPerson", "posixAccount"}, "cn": {"John"}, "sn": {"Doe"}, "uid": {"john"}, "uidNumber": {"1000"}, "homeDirectory": {"/home/john"}},
+			expect: []string{"gidNumber"},
+		},
+		{
+			name:   "not allowed",
+			attrs:  map[string][]string{"objectClass": {"person"}, "cn": {"John"}, "sn": {"Doe"}, "mail": {"john@example.com"}},
+			expect: []string{"mail"},
+		},
+		{
+			name:  "extensibleObject allows everything",
+			attrs: map[string][]string{"objectClass": {"person", "extensibleObject"}, "cn": {"John"}, "sn": {"Doe"}, "mail": {"john@example.com"}},
+		},
+		{
+			name:   "unknown attribute",
+			attrs:  map[string][]string{"objectClass": {"person", "extensibleObject"}, "cn": {"John"}, "sn": {"Doe"}, "shoeSize": {"42"}},
+			expect: []string{"shoeSize"},
+		},
+		{
+			name:   "unknown objectClass",
+			attrs:  map[string][]string{"objectClass": {"person", "wizard"}, "cn": {"John"}, "sn": {"Doe"}},
+			expect: []string{"objectClass"},
+		},
+		{
+			name:   "no structural class",
+			attrs:  map[string][]string{"objectClass": {"top", "extensibleObject"}, "cn": {"John"}},
+			expect: []string{"objectClass"},
+		},
+		{
+			name:   "two structural chains",
+			attrs:  map[string][]string{"objectClass": {"person", "applicationProcess"}, "cn": {"John"}, "sn": {"Doe"}},
+			expect: []string{"objectClass"},
+		},
+		{
+			name:   "single value",
+			attrs:  map[string][]string{"objectClass": {"inetOrgPerson"}, "cn": {"John"}, "sn": {"Doe"}, "displayName": {"John", "Johnny"}},
+			expect: []string{"displayName"},
+		},
+		{
+			name: "integer",
+			attrs: map[string][]string{"objectClass": {"inetOrgPerson", "posixAccount"}, "cn": {"John"}, "sn": {"Doe"}, "uid": {"john"},
+				"uidNumber": {"01000"}, "gidNumber": {"abc"}, "homeDirectory": {"/home/john"}},
+			expect: []string{"uidNumber", "gidNumber"},
+		},
+		{
+			name:   "boolean",
+			attrs:  map[string][]string{"objectClass": {"person", "pwdPolicyTest"}, "cn": {"John"}, "sn": {"Doe"}, "pwdReset": {"true"}},
+			expect: []string{"pwdReset"},
+		},
+		{
+			name:  "generalized time",
+			attrs: map[string][]string{"objectClass": {"person", "pwdPolicyTest"}, "cn": {"John"}, "sn": {"Doe"}, "pwdReset": {"TRUE"}, "pwdChangedTime": {"20240131235959.5+0100"}},
+		},
+		{
+			name:   "invalid generalized time",
+			attrs:  map[string][]string{"objectClass": {"person", "pwdPolicyTest"}, "cn": {"John"}, "sn": {"Doe"}, "pwdChangedTime": {"2024-01-31"}},
+			expect: []string{"pwdChangedTime"},
+		},
+		{
+			name:   "dn inherited from the superior",
+			attrs:  map[string][]string{"objectClass": {"person"}, "cn": {"John"}, "sn": {"Doe"}, "seeAlso": {"cn=Jane,dc=example,dc=com", "not a dn"}},
+			expect: []string{"seeAlso"},
+		},
+		{
+			name:   "no user modification",
+			attrs:  map[string][]string{"objectClass": {"person"}, "cn": {"John"}, "sn": {"Doe"}, "createTimestamp": {"20240131235959Z"}},
+			expect: []string{"createTimestamp"},
+		},
+	}
+
+	for _, value := range values {
+		errs := parser.ValidateEntry(value.attrs)
+		if len(errs) != len(value.expect) {
+			t.Errorf("%s: expect %d problems, get %v", value.name, len(value.expect), errs)
+			continue
+		}
+		for _, attr := range value.expect {
+			found := false
+			for _, err := range errs {
+				if strings.EqualFold(err.Attribute, attr) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("%s: expect a problem with %s, get %v", value.name, attr, errs)
+			}
+		}
+	}
+}
+
+func TestApplyChanges(t *testing.T) {
+	attrs := map[string][]string{"objectClass": {"person"}, "cn": {"John", "Johnny"}, "sn": {"Doe"}, "description": {"old"}}
+	result := ApplyChanges(attrs, []AttributeChange{
+		{Operation: ModifyAdd, Attribute: "CN", Values: []string{"Jo"}},
+		{Operation: ModifyDelete, Attribute: "cn", Values: []string{"Johnny"}},
+		{Operation: ModifyReplace, Attribute: "sn", Values: []string{"Smith"}},
+		{Operation: ModifyDelete, Attribute: "description"},
+		{Operation: ModifyAdd, Attribute: "title", Values: []string{"Dr"}},
+	})
+	if strings.Join(result["cn"], ",") != "John,Jo" || strings.Join(result["sn"], ",") != "Smith" ||
+		strings.Join(result["title"], ",") != "Dr" {
+		t.Errorf("unexpected result %v", result)
+	}
+	if _, exist := result["description"]; exist {
+		t.Errorf("description should be removed, get %v", result)
+	}
+	if len(attrs["cn"]) != 2 {
+		t.Errorf("input must not change, get %v", attrs)
+	}
+}
+
+func TestValidateModify(t *testing.T) {
+	parser := validatorSchema(t)
+	// read with the operational attributes, breaking the rules for a complete entry
+	stored := map[string][]string{"objectClass": {"inetOrgPerson"}, "cn": {"John"}, "sn": {"Doe"},
+		"createTimestamp": {"20240131235959Z"}, "shoeSize": {"42"}, "displayName": {"John", "Johnny"}}
+	values := []struct {
+		name    string
+		changes []AttributeChange
+		expect  []string
+	}{
+		{
+			name:    "unrelated attribute",
+			changes: []AttributeChange{{Operation: ModifyAdd, Attribute: "description", Values: []string{"new"}}},
+		},
+		{
+			name:    "delete value",
+			changes: []AttributeChange{{Operation: ModifyDelete, Attribute: "seeAlso", Values: []string{"not a dn"}}},
+		},
+		{
+			name:    "no user modification",
+			changes: []AttributeChange{{Operation: ModifyReplace, Attribute: "createTimestamp", Values: []string{"20250101000000Z"}}},
+			expect:  []string{"createTimestamp"},
+		},
+		{
+			name:    "unknown attribute",
+			changes: []AttributeChange{{Operation: ModifyAdd, Attribute: "eyeColor", Values: []string{"blue"}}},
+			expect:  []string{"eyeColor"},
+		},
+		{
+			name:    "syntax",
+			changes: []AttributeChange{{Operation: ModifyAdd, Attribute: "seeAlso", Values: []string{"not a dn"}}},
+			expect:  []string{"seeAlso"},
+		},
+		{
+			name:    "single value",
+			changes: []AttributeChange{{Operation: ModifyAdd, Attribute: "displayName", Values: []string{"Jo"}}},
+			expect:  []string{"displayName"},
+		},
+		{
+			name:    "single value repaired",
+			changes: []AttributeChange{{Operation: ModifyReplace, Attribute: "displayName", Values: []string{"Jo"}}},
+		},
+		{
+			name:    "not allowed by the objectClasses",
+			changes: []AttributeChange{{Operation: ModifyAdd, Attribute: "uidNumber", Values: []string{"1000"}}},
+			expect:  []string{"uidNumber"},
+		},
+		{
+			name:    "delete must",
+			changes: []AttributeChange{{Operation: ModifyDelete, Attribute: "sn"}},
+			expect:  []string{"sn"},
+		},
+		{
+			name:    "replace must with nothing",
+			changes: []AttributeChange{{Operation: ModifyReplace, Attribute: "surname"}},
+			expect:  []string{"sn"},
+		},
+		{
+			name:    "no structural class",
+			changes: []AttributeChange{{Operation: ModifyReplace, Attribute: "objectClass", Values: []string{"top", "extensibleObject"}}},
+			expect:  []string{"objectClass"},
+		},
+		{
+			name:    "two structural chains",
+			changes: []AttributeChange{{Operation: ModifyAdd, Attribute: "objectClass", Values: []string{"applicationProcess"}}},
+			expect:  []string{"objectClass"},
+		},
+		{
+			name:    "new auxiliary class needs its MUSTs",
+			changes: []AttributeChange{{Operation: ModifyAdd, Attribute: "objectClass", Values: []string{"posixAccount"}}},
+			expect:  []string{"uid", "uidNumber", "gidNumber", "homeDirectory"},
+		},
+		{
+			name: "new auxiliary class with its MUSTs",
+			changes: []AttributeChange{
+				{Operation: ModifyAdd, Attribute: "objectClass", Values: []string{"posixAccount"}},
+				{Operation: ModifyAdd, Attribute: "uid", Values: []string{"john"}},
+				{Operation: ModifyAdd, Attribute: "uidNumber", Values: []string{"1000"}},
+				{Operation: ModifyAdd, Attribute: "gidNumber", Values: []string{"1000"}},
+				{Operation: ModifyAdd, Attribute: "homeDirectory", Values: []string{"/home/john"}},
+			},
+		},
+	}
+	for _, value := range values {
+		errs := parser.ValidateModify(stored, value.changes)
+		if len(errs) != len(value.expect) {
+			t.Errorf("%s: expect %d problems, get %v", value.name, len(value.expect), errs)
+			continue
+		}
+		for _, attr := range value.expect {
+			if !slices.ContainsFunc(errs, func(err ValidationError) bool { return strings.EqualFold(err.Attribute, attr) }) {
+				t.Errorf("%s: expect a problem with %s, get %v", value.name, attr, errs)
+			}
+		}
+	}
+}
+
+func TestValidateEntryServerPopulated(t *testing.T) {
+	parser := NewObjectClassParser()
+	attributes := []string{
+		"( 2.5.4.0 NAME 'objectClass' SYNTAX '1.3.6.1.4.1.1466.115.121.1.38' )",
+		"( 2.5.4.3 NAME 'cn' SYNTAX '1.3.6.1.4.1.1466.115.121.1.15' SINGLE-VALUE )",
+		"( 1.2.840.113556.1.2.1 NAME 'instanceType' SYNTAX '1.3.6.1.4.1.1466.115.121.1.27' SINGLE-VALUE NO-USER-MODIFICATION )",
+		"( 1.2.840.113556.1.2.281 NAME 'nTSecurityDescriptor' SYNTAX '1.2.840.113556.1.4.907' SINGLE-VALUE )",
+		"( 1.2.840.113556.1.4.782 NAME 'objectCategory' SYNTAX '1.3.6.1.4.1.1466.115.121.1.12' SINGLE-VALUE )",
+		"( 1.2.840.113556.1.2.2 NAME 'objectGUID' SYNTAX '1.3.6.1.4.1.1466.115.121.1.40' SINGLE-VALUE NO-USER-MODIFICATION )",
+	}
+	classes := []string{
+		"( 2.5.6.0 NAME 'top' ABSTRACT MUST (objectClass $ instanceType $ nTSecurityDescriptor $ objectCategory ) MAY ( cn $ objectGUID ) )",
+		"( 1.2.840.113556.1.5.8 NAME 'group' SUP top STRUCTURAL MUST ( objectGUID ) )",
+	}
+	for _, attr := range attributes {
+		if _, err := parser.ParseAttributeType(attr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, class := range classes {
+		if _, err := parser.ParseObjectClass(class); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if errs := parser.ValidateEntry(map[string][]string{"objectClass": {"top", "group"}, "cn": {"staff"}}); len(errs) > 0 {
+		t.Errorf("expect the server to fill in the attributes, get %v", errs)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	"com.ldap/management/cmd"
@@ -11,10 +12,12 @@ func main() {
 	app := cli.NewApp()
 	app.Commands = []*cli.Command{
 		cmd.WebCommand,
+		cmd.ImportCommand,
 	}
 
 	err := app.Run(os.Args)
 	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
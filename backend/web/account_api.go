@@ -0,0 +1,112 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+
+	"com.ldap/management/ldap"
+	"github.com/gin-gonic/gin"
+	gldap "github.com/go-ldap/ldap/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultAccountTemplate renders posix accounts when the request names no template.
+const defaultAccountTemplate = "posix-user"
+
+type posixAccountBody struct {
+	ldap.PosixAccount
+	Template string `json:"template"`
+}
+
+// CreatePosixAccount adds a posixAccount with allocated uidNumber and
+// gidNumber. Without primaryGroup a group named after the uid is created.
+func (r *Router) CreatePosixAccount(c *gin.Context) {
+	var body posixAccountBody
+	if err := c.ShouldBindBodyWithJSON(&body); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if body.Template == "" {
+		body.Template = defaultAccountTemplate
+	}
+	tmpl, exist := r.Templates[body.Template]
+	if !exist {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "unknown template " + body.Template})
+		return
+	}
+	accounts, err := ldap.NewAccounts(r.ldapOf(c))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	account, err := accounts.CreateUser(tmpl, body.PosixAccount)
+	switch {
+	case err == nil:
+	case errors.Is(err, ldap.ErrIDRangeExhausted):
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"message": err.Error()})
+		return
+	case gldap.IsErrorWithCode(err, gldap.LDAPResultEntryAlreadyExists):
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"message": err.Error()})
+		return
+	default:
+		abortWithLdapError(c, err)
+		return
+	}
+	log.Infof("create posix account %s with primary group %s", account.DN, account.GroupDN)
+	c.JSON(http.StatusCreated, account)
+}
+
+type accountBody struct {
+	DN string `json:"dn"`
+}
+
+// AccountStatus returns whether the account dn is locked, disabled or has an expired password.
+func (r *Router) AccountStatus(c *gin.Context) {
+	dn := c.Query("dn")
+	if dn == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "please give dn paramter"})
+		return
+	}
+	status, err := r.ldapOf(c).AccountStatus(dn)
+	if err != nil {
+		abortWithAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// ChangeAccount returns the handler applying action, one of lock, unlock,
+// disable and enable, to the account in the body. The new status is returned.
+func (r *Router) ChangeAccount(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body accountBody
+		if err := c.ShouldBindBodyWithJSON(&body); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		if body.DN == "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "please input which dn to " + action})
+			return
+		}
+		log.Infof("%s account %s", action, body.DN)
+		status, err := r.ldapOf(c).ChangeAccount(body.DN, action)
+		if err != nil {
+			abortWithAccountError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// abortWithAccountError maps missing entries to 404 and locks the server can not do to 400.
+func abortWithAccountError(c *gin.Context, err error) {
+	switch {
+	case gldap.IsErrorWithCode(err, gldap.LDAPResultNoSuchObject):
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": err.Error()})
+	case errors.Is(err, ldap.ErrLockNotSupported):
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	default:
+		abortWithLdapError(c, err)
+	}
+}
@@ -0,0 +1,149 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+
+	"com.ldap/management/ldap"
+	"github.com/gin-gonic/gin"
+	gldap "github.com/go-ldap/ldap/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+// groupsOf returns the group manager of the caller's session, it answers the
+// request itself when the base DN is unknown.
+func (r *Router) groupsOf(c *gin.Context) (*ldap.Groups, bool) {
+	groups, err := ldap.NewGroups(r.ldapOf(c))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return nil, false
+	}
+	return groups, true
+}
+
+// abortWithGroupError maps missing entries to 404 and the empty group constraint to 409.
+func abortWithGroupError(c *gin.Context, err error) {
+	switch {
+	case gldap.IsErrorWithCode(err, gldap.LDAPResultNoSuchObject):
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": err.Error()})
+	case errors.Is(err, ldap.ErrLastMember):
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"message": err.Error()})
+	default:
+		abortWithLdapError(c, err)
+	}
+}
+
+// ListGroups returns the groupOfNames, groupOfUniqueNames and posixGroup entries below base.
+func (r *Router) ListGroups(c *gin.Context) {
+	groups, ok := r.groupsOf(c)
+	if !ok {
+		return
+	}
+	list, err := groups.List(c.Query("base"))
+	if err != nil {
+		abortWithGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"groups": list})
+}
+
+// GroupMembers returns the members of the group dn with their display names.
+func (r *Router) GroupMembers(c *gin.Context) {
+	dn := c.Query("dn")
+	if dn == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "please give dn paramter"})
+		return
+	}
+	groups, ok := r.groupsOf(c)
+	if !ok {
+		return
+	}
+	members, err := groups.Members(dn)
+	if err != nil {
+		abortWithGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"dn": dn, "members": members})
+}
+
+// GroupMemberships returns the groups dn belongs to, directly and through
+// nested groups, with the path reaching each of them. method may force
+// memberOf, in-chain or walk.
+func (r *Router) GroupMemberships(c *gin.Context) {
+	dn := c.Query("dn")
+	if dn == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "please give dn paramter"})
+		return
+	}
+	method := c.Query("method")
+	switch method {
+	case "", ldap.MembershipMemberOf, ldap.MembershipInChain, ldap.MembershipWalk:
+	default:
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "method must be memberOf, in-chain or walk"})
+		return
+	}
+	groups, ok := r.groupsOf(c)
+	if !ok {
+		return
+	}
+	graph, err := groups.Memberships(dn, method)
+	if err != nil {
+		abortWithGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, graph)
+}
+
+type membersBody struct {
+	DN      string   `json:"dn"`
+	Members []string `json:"members"` // DNs or uids
+}
+
+func (r *Router) bindMembers(c *gin.Context) (membersBody, bool) {
+	var body membersBody
+	if err := c.ShouldBindBodyWithJSON(&body); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return body, false
+	}
+	if body.DN == "" || len(body.Members) == 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "please input the group dn and the members"})
+		return body, false
+	}
+	return body, true
+}
+
+// AddGroupMembers adds members with the member attribute of the group type.
+func (r *Router) AddGroupMembers(c *gin.Context) {
+	body, ok := r.bindMembers(c)
+	if !ok {
+		return
+	}
+	groups, ok := r.groupsOf(c)
+	if !ok {
+		return
+	}
+	log.Infof("add %v to group %s", body.Members, body.DN)
+	if err := groups.AddMembers(body.DN, body.Members); err != nil {
+		abortWithGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// RemoveGroupMembers removes members, see ldap.Groups.RemoveMembers for the last member.
+func (r *Router) RemoveGroupMembers(c *gin.Context) {
+	body, ok := r.bindMembers(c)
+	if !ok {
+		return
+	}
+	groups, ok := r.groupsOf(c)
+	if !ok {
+		return
+	}
+	log.Infof("remove %v from group %s", body.Members, body.DN)
+	if err := groups.RemoveMembers(body.DN, body.Members); err != nil {
+		abortWithGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
@@ -1,33 +1,65 @@
 package web
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"com.ldap/management/ldap"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	gldap "github.com/go-ldap/ldap/v3"
 	"github.com/golang-jwt/jwt/v5"
 	log "github.com/sirupsen/logrus"
 )
 
 type Router struct {
 	Engine *gin.Engine
-	Ldap   ldap.LdapOperation
+	Sessions *SessionStore
+	Transport ldap.TransportOptions // default transport, a login may only tighten it
+	AllowClientTransport bool // let a login weaken the transport, trust its own CA or skip verification
+	Profile ldap.ServerProfile // directory layout, a login may override the base DN
+	Schemas *ldap.SchemaCache // schema shared by the sessions of a server
+	Templates map[string]*ldap.EntryTemplate // entry templates by name
+	LegacyCommaSplit bool // split string values of the old add format at commas
 	SecurityKey []byte
 }
 
+// PasswordChangeLifetime limits the session of a user who must change the password.
+const PasswordChangeLifetime = 5 * time.Minute
+
+const sessionKey = "session"
+
+// maxPageSize bounds the page size a client may ask for
+const maxPageSize = 1000
+
 func NewRouter() *Router {
 	engine := gin.New()
 	engine.SetTrustedProxies(nil)
 	return &Router{
 		Engine: engine,
+		Sessions: NewSessionStore(30 * time.Minute),
+		Profile: ldap.DefaultServerProfile(),
+		Schemas: ldap.NewSchemaCache(),
+		Templates: make(map[string]*ldap.EntryTemplate),
 		SecurityKey: []byte("your_secret_key"),
 	}
 }
 
+// session returns the session resolved by AuthRequire for this request.
+func (r *Router) session(c *gin.Context) *Session {
+	return c.MustGet(sessionKey).(*Session)
+}
+
+// ldapOf returns the LDAP connection owned by the caller's session.
+func (r *Router) ldapOf(c *gin.Context) *ldap.LDAPOperation {
+	return r.session(c).Ldap
+}
+
 func (r *Router) Login(c *gin.Context) {
 	username := c.Request.FormValue("username")
 	password := c.Request.FormValue("password")
@@ -42,33 +74,157 @@ func (r *Router) Login(c *gin.Context) {
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Username and password are required"})
 		return
 	}
-	r.Ldap, _ = ldap.NewLDAPOperation(username, password, lhost, lport)
+	transport, err := r.loginTransport(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	op, _ := ldap.NewLDAPOperation(username, password, lhost, lport)
+	op.Transport = transport
+	op.Profile = r.Profile
+	op.Schemas = r.Schemas
+	if baseDN := c.Request.FormValue("baseDN"); baseDN != "" {
+		op.Profile.BaseDN = baseDN
+	}
 
-	if err := r.Ldap.Connect(); err != nil {
+	if err := op.Connect(); err != nil {
 		log.Println("Failed to connect to LDAP server:", err)
+		op.Close()
+		// an expired password or a locked account is told, other failures are not
+		var policyErr *ldap.PolicyError
+		if errors.As(err, &policyErr) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": policyErr.Policy.Message, "passwordPolicy": policyErr.Policy})
+			return
+		}
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
-	err = r.Ldap.Authenicate()
+	// after a reset the server allows nothing but changing the password
+	if op.BindPolicy != nil && op.BindPolicy.MustChange {
+		r.passwordOnlyLogin(c, username, op)
+		return
+	}
+	err = op.Authenicate()
 	if err != nil {
 		log.Println("Authentication failed:", err)
+		op.Close()
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"username": username,
-		"exp":      time.Now().Add(7*24 * time.Hour).Unix(),
-	})
-	tokenString, err := token.SignedString(r.SecurityKey)
+	session, err := r.Sessions.Create(username, op)
+	if err != nil {
+		log.Println("Failed to create session:", err)
+		op.Close()
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+
+	tokenString, err := r.issueToken(session)
 	if err != nil {
 		log.Println("Failed to sign token:", err)
+		r.Sessions.Close(session.ID)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 	// retrieve all schema
-	go r.Ldap.GetObjectClassAttributes()
-	c.JSON(http.StatusOK, gin.H{"message": "Login successful","token": tokenString})
+	go op.GetObjectClassAttributes()
+	response := gin.H{"message": "Login successful","token": tokenString}
+	// e.g. the password expires soon or only grace logins are left
+	if op.BindPolicy != nil {
+		response["passwordPolicy"] = op.BindPolicy
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// passwordOnlyLogin answers the login of a user who must change the password
+// with 403 and the token of a short-lived session that only allows POST
+// /password, so the change can be made with the bound connection.
+func (r *Router) passwordOnlyLogin(c *gin.Context, username string, op *ldap.LDAPOperation) {
+	session, err := r.Sessions.CreatePasswordOnly(username, op, PasswordChangeLifetime)
+	if err != nil {
+		log.Println("Failed to create session:", err)
+		op.Close()
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+	tokenString, err := r.issueToken(session)
+	if err != nil {
+		log.Println("Failed to sign token:", err)
+		r.Sessions.Close(session.ID)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": op.BindPolicy.Message, "passwordPolicy": op.BindPolicy,
+		"token": tokenString, "passwordChangeOnly": true})
+}
+
+// loginTransport applies the transport fields of the login form on top of the server defaults.
+// The login is not authenticated yet, so unless AllowClientTransport is set it may only make
+// the connection stricter: plain may become starttls or ldaps, insecureSkipVerify may only be
+// turned off, and the trusted CAs and the verified server name stay as configured.
+func (r *Router) loginTransport(c *gin.Context) (ldap.TransportOptions, error) {
+	transport := r.Transport
+	configured, err := ldap.ParseTransportMode(transport.Mode)
+	if err != nil {
+		return transport, err
+	}
+	transport.Mode = configured
+	if mode := c.Request.FormValue("transport"); mode != "" {
+		if transport.Mode, err = ldap.ParseTransportMode(mode); err != nil {
+			return transport, err
+		}
+		if !r.AllowClientTransport && configured != ldap.TransportPlain && transport.Mode == ldap.TransportPlain {
+			return transport, fmt.Errorf("transport %s is weaker than the configured %s", transport.Mode, configured)
+		}
+	}
+	if serverName := c.Request.FormValue("serverName"); serverName != "" {
+		if !r.AllowClientTransport && serverName != transport.ServerName {
+			return transport, errors.New("serverName can only be set by the server unless --allow-client-transport is given")
+		}
+		transport.ServerName = serverName
+	}
+	if caCert := c.Request.FormValue("caCert"); caCert != "" {
+		if !r.AllowClientTransport {
+			return transport, errors.New("caCert can only be set by the server unless --allow-client-transport is given")
+		}
+		transport.CAPem = caCert
+	}
+	if insecure := c.Request.FormValue("insecureSkipVerify"); insecure != "" {
+		skip, err := strconv.ParseBool(insecure)
+		if err != nil {
+			return transport, fmt.Errorf("invalid insecureSkipVerify value %q", insecure)
+		}
+		if skip && !transport.InsecureSkipVerify && !r.AllowClientTransport {
+			return transport, errors.New("insecureSkipVerify can only be enabled by the server unless --allow-client-transport is given")
+		}
+		transport.InsecureSkipVerify = skip
+	}
+	return transport, nil
+}
+
+// issueToken signs the JWT carrying the session id, it ends with the session
+// when the session has a fixed end.
+func (r *Router) issueToken(session *Session) (string, error) {
+	expires := time.Now().Add(7*24 * time.Hour)
+	if !session.Expires.IsZero() {
+		expires = session.Expires
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"username": session.Username,
+		"sid":      session.ID,
+		"exp":      expires.Unix(),
+	})
+	return token.SignedString(r.SecurityKey)
+}
+
+// passwordOnlyRoute reports whether a password-only session may call the route.
+func passwordOnlyRoute(c *gin.Context) bool {
+	switch c.Request.URL.Path {
+	case "/api/v1/password", "/api/v1/logout":
+		return c.Request.Method == http.MethodPost
+	}
+	return false
 }
 
 func (r *Router) AuthRequire() gin.HandlerFunc {
@@ -80,18 +236,13 @@ func (r *Router) AuthRequire() gin.HandlerFunc {
 			return
 		}
 
-		if tokenString == "" || r.Ldap == nil{
+		if tokenString == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
 			return
 		}
 
-		ldop := r.Ldap.(*ldap.LDAPOperation)
-		if ldop.User == "" || ldop.Pwd == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid information, please re-login."})
-			return
-		}
-
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		claims := jwt.MapClaims{}
+		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 			}
@@ -102,14 +253,37 @@ func (r *Router) AuthRequire() gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			return
 		}
+
+		sid, _ := claims["sid"].(string)
+		session, err := r.Sessions.Get(sid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid information, please re-login."})
+			return
+		}
+		if session.PasswordOnly && !passwordOnlyRoute(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "The password must be changed first."})
+			return
+		}
+		c.Set(sessionKey, session)
 		c.Next()
 	}
 }
 
+func (r *Router) Logout(c *gin.Context) {
+	if err := r.Sessions.Close(r.session(c).ID); err != nil {
+		log.Warnln("close session error:", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logout successful"})
+}
+
 func (r *Router) Recovery() gin.HandlerFunc {
 	return func(c *gin.Context){
 		defer func() {
 			if rec := recover(); rec != nil {
+				// a handler cuts a response it already started, let net/http drop the connection
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
 				log.Errorln("recovery..", rec)
 				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal abnormal."})
 			}
@@ -118,21 +292,111 @@ func (r *Router) Recovery() gin.HandlerFunc {
 	}
 }
 
+// Add creates an entry from a JSON object of attribute values, e.g.
+// {"DN": ["cn=a,dc=example,dc=com"], "objectClass": ["top", "person"], "jpegPhoto::": ["base64"]}.
+// A plain string value is the old format, it is split at commas only when
+// LegacyCommaSplit is enabled.
 func (r *Router) Add(c *gin.Context) {
-	var body map[string]string
+	var body map[string]json.RawMessage
 	
 	if err := c.ShouldBindBodyWithJSON(&body); err != nil {
 		c.AbortWithStatus(http.StatusBadRequest)
 		return
 	}
-	log.Println("receive msg: ", body)
-	if err := r.Ldap.AddRecord(body); err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message":err.Error()})
+	record := make(map[string][]string, len(body))
+	for k, raw := range body {
+		var values []string
+		if err := json.Unmarshal(raw, &values); err == nil {
+			record[k] = values
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("value of %s must be a string or an array of strings", k)})
+			return
+		}
+		if r.LegacyCommaSplit && k != "DN" {
+			record[k] = ldap.SplitLegacyValue(value)
+		} else {
+			record[k] = []string{strings.TrimSpace(value)}
+		}
+	}
+	log.Println("receive msg: ", record)
+	if err := r.ldapOf(c).AddRecord(record); err != nil {
+		abortWithLdapError(c, err)
+		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{"message":"success"})
 }
 
+// abortWithLdapError answers 422 with the single problems for schema
+// violations and 500 for everything else.
+func abortWithLdapError(c *gin.Context, err error) {
+	var violations ldap.ValidationErrors
+	if errors.As(err, &violations) {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error(), "errors": violations})
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+}
+
+type modifyBody struct {
+	DN      string                 `json:"dn"`
+	Changes []ldap.AttributeChange `json:"changes"`
+}
+
+func (r *Router) Modify(c *gin.Context) {
+	var body modifyBody
+	if err := c.ShouldBindBodyWithJSON(&body); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if body.DN == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "please input which dn to modify"})
+		return
+	}
+	if err := ldap.ValidateChanges(body.Changes); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	log.Info("going to modify ", body.DN)
+	if err := r.ldapOf(c).ModifyRecord(body.DN, body.Changes); err != nil {
+		abortWithLdapError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+type renameBody struct {
+	DN           string `json:"dn"`
+	NewRDN       string `json:"newRDN"`
+	NewSuperior  string `json:"newSuperior"`
+	DeleteOldRDN *bool  `json:"deleteOldRDN"` // defaults to true
+}
+
+func (r *Router) Rename(c *gin.Context) {
+	var body renameBody
+	if err := c.ShouldBindBodyWithJSON(&body); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if body.DN == "" || (body.NewRDN == "" && body.NewSuperior == "") {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "please input the dn and a new rdn or new superior"})
+		return
+	}
+	deleteOldRDN := body.DeleteOldRDN == nil || *body.DeleteOldRDN
+	log.Infof("going to rename %s to %s under %s", body.DN, body.NewRDN, body.NewSuperior)
+	newDN, err := r.ldapOf(c).RenameRecord(body.DN, body.NewRDN, body.NewSuperior, deleteOldRDN)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "dn": newDN})
+}
+
 func (r *Router) Delete(c *gin.Context) {
 	dn := c.Query("dn")
 	log.Info("going to delete ", dn)
@@ -141,8 +405,25 @@ func (r *Router) Delete(c *gin.Context) {
 		return
 	}
 	
-	if err := r.Ldap.DeleteRecord(dn); err != nil {
+	recursive, _ := strconv.ParseBool(c.Query("recursive"))
+	dryRun, _ := strconv.ParseBool(c.Query("dryRun"))
+	if recursive || dryRun {
+		dns, err := r.ldapOf(c).DeleteTree(dn, dryRun)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message":err.Error(), "deleted": dns})
+			return
+		}
+		if dryRun {
+			c.JSON(http.StatusOK, gin.H{"message":"dry run, nothing deleted", "dns": dns})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"message":"success", "deleted": dns})
+		return
+	}
+
+	if err := r.ldapOf(c).DeleteRecord(dn); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message":err.Error()})
+		return
 	}
 
 	c.JSON(http.StatusAccepted, gin.H{"message":"success"})
@@ -193,22 +474,65 @@ func (r *Router) SetupRouter() {
 
 		// login
 		groupRoute.POST("/login", r.Login)
+
+		// logout, closes the caller's LDAP session
+		groupRoute.POST("/logout", r.Logout)
 		
+		// search with base, scope, filter and attributes
+		groupRoute.GET("/ldap/search", r.Search)
+
 		// search account attributes
 		groupRoute.GET("/ldap/dn", r.SearchEntryAttribute)
 		
 		// get all schema
-		groupRoute.GET("/schema", func (c *gin.Context)  {
-			operation := r.Ldap.(*ldap.LDAPOperation)
+		groupRoute.GET("/schema", r.Schema)
 
-			c.JSON(http.StatusOK, gin.H{"schemas":operation.ObjParser.Objects})
-		})
+		// readiness of the schema and reload on demand
+		groupRoute.GET("/schema/status", r.SchemaStatus)
+		groupRoute.POST("/schema/refresh", r.RefreshSchema)
+
+		// one attribute type with inherited syntax and matching rules
+		groupRoute.GET("/schema/attributes/:name", r.SchemaAttribute)
 		// add account
 		groupRoute.POST("/ldap/add", r.Add)
 
 		// delete account
 		groupRoute.DELETE("/ldap/del", r.Delete)
 		// update account
+		groupRoute.PATCH("/ldap/dn", r.Modify)
+
+		// rename or move account
+		groupRoute.POST("/ldap/rename", r.Rename)
+
+		// self-service password change and reset by an administrator
+		groupRoute.POST("/password", r.ChangePassword)
+		groupRoute.POST("/password/reset", r.ResetPassword)
+		groupRoute.GET("/password/policy", r.PasswordPolicy)
+
+		// groups and their members
+		groupRoute.GET("/groups", r.ListGroups)
+		groupRoute.GET("/groups/members", r.GroupMembers)
+		groupRoute.GET("/groups/memberships", r.GroupMemberships)
+		groupRoute.POST("/groups/members", r.AddGroupMembers)
+		groupRoute.DELETE("/groups/members", r.RemoveGroupMembers)
+
+		// entry templates and their rendering into add requests
+		groupRoute.GET("/templates", r.ListTemplates)
+		groupRoute.POST("/templates/:name/render", r.RenderTemplate)
+
+		// posix accounts with allocated uid and gid numbers
+		groupRoute.POST("/accounts/posix", r.CreatePosixAccount)
+		groupRoute.GET("/accounts/status", r.AccountStatus)
+		groupRoute.POST("/accounts/lock", r.ChangeAccount(ldap.AccountLock))
+		groupRoute.POST("/accounts/unlock", r.ChangeAccount(ldap.AccountUnlock))
+		groupRoute.POST("/accounts/disable", r.ChangeAccount(ldap.AccountDisable))
+		groupRoute.POST("/accounts/enable", r.ChangeAccount(ldap.AccountEnable))
+
+		// export entries as LDIF
+		groupRoute.GET("/ldap/export", r.Export)
+
+		// import LDIF
+		groupRoute.POST("/ldap/import", r.Import)
 	}
 }
 
@@ -218,23 +542,69 @@ func (r *Router) SearchEntryAttribute(c *gin.Context) {
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg": "please give dn paramter"})
 		return
 	}
-	attrs, err := r.Ldap.GetAttrOfObjectClass(dn)
+	op := r.ldapOf(c)
+	attrs, err := op.GetAttrOfObjectClass(dn)
 	if err != nil {
 		log.Errorf("get attribute errors: %v", err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err})
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// accounts carry their lock status, the entries keep their shape for the UI
+	details := make([]entryDetail, len(attrs))
+	for i, entry := range attrs {
+		details[i].Entry = entry
+		if !ldap.IsAccount(entry) {
+			continue
+		}
+		if status, err := op.AccountStatus(entry.DN); err != nil {
+			log.Warnf("account status of %s: %v", entry.DN, err)
+		} else {
+			details[i].AccountStatus = status
+		}
 	}
-	c.JSON(http.StatusOK, attrs)
+	c.JSON(http.StatusOK, details)
+}
+
+type entryDetail struct {
+	*gldap.Entry
+	AccountStatus *ldap.AccountStatus `json:"accountStatus,omitempty"`
 }
 
 func (r *Router) SearchAllEntry(c *gin.Context) {
-	if r.Ldap == nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "LDAP connection is not established"})
+		op := r.ldapOf(c)
+		baseDN, err := op.BaseDN()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		baseDN := "dc=example,dc=com"
 		filter := "(objectClass=*)"
 
-		entries, err := r.Ldap.Search(baseDN, filter)
+		// with pageSize the result is returned page by page together with a cursor
+		if pageSizeStr, paged := c.GetQuery("pageSize"); paged {
+			pageSize, err := strconv.ParseUint(pageSizeStr, 10, 32)
+			if err != nil || pageSize == 0 || pageSize > maxPageSize {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("pageSize must be between 1 and %d", maxPageSize)})
+				return
+			}
+			page, err := op.SearchPaged(ldap.SearchOptions{
+				BaseDN: baseDN,
+				Filter: filter,
+				PageSize: uint32(pageSize),
+				Cursor: c.Query("cursor"),
+			})
+			if errors.Is(err, ldap.ErrInvalidCursor) {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, page)
+			return
+		}
+
+		entries, err := op.Search(baseDN, filter)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -248,7 +618,81 @@ func (r *Router) SearchAllEntry(c *gin.Context) {
 		c.JSON(http.StatusOK, entries)
 }
 
+// Search runs a search with caller supplied base, scope, filter, attributes and limits.
+func (r *Router) Search(c *gin.Context) {
+	op := r.ldapOf(c)
+	opts := ldap.SearchOptions{
+		BaseDN: c.Query("base"),
+		Scope: c.Query("scope"),
+		Filter: c.DefaultQuery("filter", "(objectClass=*)"),
+		DerefAliases: c.Query("deref"),
+		Cursor: c.Query("cursor"),
+	}
+	if opts.BaseDN == "" {
+		var err error
+		if opts.BaseDN, err = op.BaseDN(); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if _, err := gldap.CompileFilter(opts.Filter); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid filter", "filter": opts.Filter, "detail": err.Error()})
+		return
+	}
+	if _, err := ldap.ParseScope(opts.Scope); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := ldap.ParseDeref(opts.DerefAliases); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// attributes may be repeated or given as a comma separated list
+	for _, attrs := range c.QueryArray("attrs") {
+		for _, attr := range strings.Split(attrs, ",") {
+			if attr = strings.TrimSpace(attr); attr != "" {
+				opts.Attributes = append(opts.Attributes, attr)
+			}
+		}
+	}
+	for name, limit := range map[string]*int{"sizeLimit": &opts.SizeLimit, "timeLimit": &opts.TimeLimit} {
+		if str := c.Query(name); str != "" {
+			value, err := strconv.ParseUint(str, 10, 31)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s %s", name, str)})
+				return
+			}
+			*limit = int(value)
+		}
+	}
+	if str := c.Query("pageSize"); str != "" {
+		pageSize, err := strconv.ParseUint(str, 10, 32)
+		if err != nil || pageSize == 0 || pageSize > maxPageSize {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("pageSize must be between 1 and %d", maxPageSize)})
+			return
+		}
+		opts.PageSize = uint32(pageSize)
+	}
+
+	page, err := op.SearchPaged(opts)
+	if errors.Is(err, ldap.ErrInvalidCursor) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if gldap.IsErrorWithCode(err, gldap.LDAPResultNoSuchObject) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
 func (r *Router) StartWebServer(port int) {
 	r.SetupRouter()
+	r.Sessions.StartReaper(time.Minute)
+	defer r.Sessions.Shutdown()
 	r.Engine.Run("0.0.0.0:" + strconv.Itoa(port))
 }
@@ -0,0 +1,57 @@
+package web
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"com.ldap/management/ldap"
+	"github.com/gin-gonic/gin"
+)
+
+func loginContext(form url.Values) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/api/v1/login", strings.NewReader(form.Encode()))
+	c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c
+}
+
+func TestLoginTransport(t *testing.T) {
+	cases := []struct {
+		name       string
+		configured ldap.TransportOptions
+		allow      bool
+		form       url.Values
+		mode       string
+		fail       bool
+	}{
+		{"defaults", ldap.TransportOptions{Mode: ldap.TransportStartTLS}, false, url.Values{}, ldap.TransportStartTLS, false},
+		{"plain to ldaps", ldap.TransportOptions{}, false, url.Values{"transport": {"ldaps"}}, ldap.TransportLDAPS, false},
+		{"starttls to ldaps", ldap.TransportOptions{Mode: ldap.TransportStartTLS}, false, url.Values{"transport": {"ldaps"}}, ldap.TransportLDAPS, false},
+		{"downgrade to plain", ldap.TransportOptions{Mode: ldap.TransportLDAPS}, false, url.Values{"transport": {"plain"}}, "", true},
+		{"skip verification", ldap.TransportOptions{Mode: ldap.TransportLDAPS}, false, url.Values{"insecureSkipVerify": {"true"}}, "", true},
+		{"enforce verification", ldap.TransportOptions{Mode: ldap.TransportLDAPS, InsecureSkipVerify: true}, false, url.Values{"insecureSkipVerify": {"false"}}, ldap.TransportLDAPS, false},
+		{"own ca", ldap.TransportOptions{Mode: ldap.TransportLDAPS}, false, url.Values{"caCert": {"pem"}}, "", true},
+		{"own server name", ldap.TransportOptions{Mode: ldap.TransportLDAPS}, false, url.Values{"serverName": {"evil.example"}}, "", true},
+		{"allowed downgrade", ldap.TransportOptions{Mode: ldap.TransportLDAPS}, true, url.Values{"transport": {"plain"}, "insecureSkipVerify": {"true"}, "caCert": {"pem"}}, ldap.TransportPlain, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &Router{Transport: tc.configured, AllowClientTransport: tc.allow}
+			transport, err := r.loginTransport(loginContext(tc.form))
+			if tc.fail {
+				if err == nil {
+					t.Fatalf("expect the override to be rejected, get %+v", transport)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if transport.Mode != tc.mode {
+				t.Errorf("expect mode %s, get %s", tc.mode, transport.Mode)
+			}
+		})
+	}
+}
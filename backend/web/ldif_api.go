@@ -0,0 +1,120 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"com.ldap/management/ldap"
+	"github.com/gin-gonic/gin"
+	gldap "github.com/go-ldap/ldap/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+// Export streams the entries matching base, scope and filter as LDIF. The
+// status is sent before the first entry, so a failed export is aborted without
+// ending the chunked response: the client sees a broken transfer, e.g. curl -f
+// exits with an error, instead of a truncated file that looks complete.
+func (r *Router) Export(c *gin.Context) {
+	op := r.ldapOf(c)
+	baseDN := c.Query("base")
+	if baseDN == "" {
+		var err error
+		if baseDN, err = op.BaseDN(); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	filter := c.DefaultQuery("filter", "(objectClass=*)")
+	if _, err := gldap.CompileFilter(filter); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid filter: %v", err)})
+		return
+	}
+	scope, err := ldap.ParseScope(c.Query("scope"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Infof("export %s with filter %s", baseDN, filter)
+	c.Header("Content-Type", "text/x-ldif; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="export.ldif"`)
+	c.Status(http.StatusOK)
+
+	writer := ldap.NewLDIFWriter(c.Writer)
+	count := 0
+	err = op.StreamSearch(c.Request.Context(), baseDN, filter, scope, func(entry *gldap.Entry) error {
+		count++
+		if err := writer.WriteEntry(entry); err != nil {
+			return err
+		}
+		// push every 100 entries to the client
+		if count%100 == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// the status is already sent, report the failure inside the LDIF and break the transfer
+		log.Errorf("export error after %d entries: %v", count, err)
+		writer.WriteComment(fmt.Sprintf("export aborted after %d entries: %v", count, err))
+		c.Writer.Flush()
+		panic(http.ErrAbortHandler)
+	}
+	c.Writer.Flush()
+}
+
+// Import applies an LDIF file, sent as the "file" form field or as the raw body.
+func (r *Router) Import(c *gin.Context) {
+	reader, err := importReader(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer reader.Close()
+	records, err := ldap.ParseLDIF(reader)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(records) == 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "no record found in LDIF"})
+		return
+	}
+
+	op := r.ldapOf(c)
+	opts := ldap.ImportOptions{}
+	opts.DryRun, _ = strconv.ParseBool(c.Query("dryRun"))
+	opts.ContinueOnError, _ = strconv.ParseBool(c.Query("continueOnError"))
+	if opts.DryRun {
+		if err := op.GetObjectClassAttributes(); err != nil {
+			log.Warnln("dry run without schema:", err)
+		}
+		opts.Schema = op.Schema()
+	}
+
+	results := ldap.ImportLDIF(op, records, opts)
+	failed := 0
+	for _, result := range results {
+		if result.Status == ldap.ImportFailed {
+			failed++
+		}
+	}
+	log.Infof("import of %d records finished, %d failed, dry run: %v", len(records), failed, opts.DryRun)
+	c.JSON(http.StatusOK, gin.H{"results": results, "total": len(records), "failed": failed, "dryRun": opts.DryRun})
+}
+
+// importReader returns the uploaded "file" of a multipart form, any other content type is
+// read as raw LDIF. The form is only parsed for multipart requests, a raw body sent as
+// application/x-www-form-urlencoded would otherwise be consumed by the form parser.
+func importReader(c *gin.Context) (io.ReadCloser, error) {
+	if c.ContentType() != "multipart/form-data" {
+		return c.Request.Body, nil
+	}
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		return nil, fmt.Errorf("missing LDIF file in form field \"file\": %v", err)
+	}
+	return file, nil
+}
@@ -0,0 +1,82 @@
+package web
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"com.ldap/management/ldap"
+	"github.com/gin-gonic/gin"
+)
+
+const importLDIF = "dn: cn=a,dc=example,dc=com\nobjectClass: top\ncn: a\n"
+
+func importContext(body io.Reader, contentType string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/api/v1/ldif/import", body)
+	c.Request.Header.Set("Content-Type", contentType)
+	return c
+}
+
+func TestImportReader(t *testing.T) {
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, _ := writer.CreateFormFile("file", "import.ldif")
+	part.Write([]byte(importLDIF))
+	writer.Close()
+
+	for _, contentType := range []string{"application/x-www-form-urlencoded", "text/x-ldif", ""} {
+		reader, err := importReader(importContext(strings.NewReader(importLDIF), contentType))
+		if err != nil {
+			t.Fatalf("%q: %v", contentType, err)
+		}
+		if body, _ := io.ReadAll(reader); string(body) != importLDIF {
+			t.Errorf("%q: expect the raw body, get %q", contentType, body)
+		}
+	}
+
+	reader, err := importReader(importContext(&form, writer.FormDataContentType()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if body, _ := io.ReadAll(reader); string(body) != importLDIF {
+		t.Errorf("expect the uploaded file, get %q", body)
+	}
+
+	writer = multipart.NewWriter(&form)
+	writer.WriteField("other", "x")
+	writer.Close()
+	if _, err := importReader(importContext(&form, writer.FormDataContentType())); err == nil {
+		t.Error("expect an error for a form without file")
+	}
+}
+
+func TestExportFailureBreaksTransfer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := NewRouter()
+	r.Engine.Use(r.Recovery())
+	// a session without connection fails after the status is sent
+	r.Engine.GET("/api/v1/ldap/export", func(c *gin.Context) {
+		c.Set(sessionKey, &Session{Ldap: &ldap.LDAPOperation{}})
+	}, r.Export)
+	server := httptest.NewServer(r.Engine)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/v1/ldap/export?base=dc=example,dc=com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Errorf("expect a broken transfer, get the complete body %q", body)
+	}
+	if !strings.Contains(string(body), "export aborted") {
+		t.Errorf("expect the reason in the body, get %q", body)
+	}
+}
@@ -0,0 +1,116 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+
+	"com.ldap/management/ldap"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+type changePasswordBody struct {
+	OldPassword string `json:"oldPassword"`
+	NewPassword string `json:"newPassword"`
+}
+
+// ChangePassword lets the logged in user change the own password, the old
+// password is required. A password-only session ends with the change, the
+// user logs in again with the new password.
+func (r *Router) ChangePassword(c *gin.Context) {
+	var body changePasswordBody
+	if err := c.ShouldBindBodyWithJSON(&body); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if body.OldPassword == "" || body.NewPassword == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "please input the old and the new password"})
+		return
+	}
+	op := r.ldapOf(c)
+	if _, err := op.ChangePassword(op.User, body.OldPassword, body.NewPassword); err != nil {
+		log.Errorf("change password of %s: %v", op.User, err)
+		abortWithPasswordError(c, http.StatusBadRequest, err)
+		return
+	}
+	log.Infof("password of %s changed", op.User)
+	if session := r.session(c); session.PasswordOnly {
+		r.Sessions.Close(session.ID)
+		c.JSON(http.StatusOK, gin.H{"message": "success, please log in with the new password"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+type resetPasswordBody struct {
+	DN          string `json:"dn"`
+	NewPassword string `json:"newPassword"` // generated when empty
+	Length      int    `json:"length"`      // of the generated password
+}
+
+// ResetPassword sets the password of another entry without knowing the old
+// one. Without newPassword a random password is generated and returned once.
+func (r *Router) ResetPassword(c *gin.Context) {
+	var body resetPasswordBody
+	if err := c.ShouldBindBodyWithJSON(&body); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if body.DN == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "please input which dn to reset"})
+		return
+	}
+	password := body.NewPassword
+	generated := password == ""
+	if generated {
+		length := body.Length
+		if length == 0 {
+			length = ldap.DefaultPasswordLength
+		}
+		var err error
+		if password, err = ldap.GeneratePassword(length); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+	op := r.ldapOf(c)
+	if _, err := op.ChangePassword(body.DN, "", password); err != nil {
+		log.Errorf("reset password of %s: %v", body.DN, err)
+		abortWithPasswordError(c, http.StatusInternalServerError, err)
+		return
+	}
+	log.Infof("password of %s reset by %s", body.DN, op.User)
+	if generated {
+		c.JSON(http.StatusOK, gin.H{"message": "success", "dn": body.DN, "password": password})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "dn": body.DN})
+}
+
+// abortWithPasswordError answers a change the password policy refused with
+// 422 and the decoded policy response, other errors with status.
+func abortWithPasswordError(c *gin.Context, status int, err error) {
+	var policyErr *ldap.PolicyError
+	if errors.As(err, &policyErr) {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error(), "passwordPolicy": policyErr.Policy})
+		return
+	}
+	c.AbortWithStatusJSON(status, gin.H{"message": err.Error()})
+}
+
+// PasswordPolicy returns the effective password policy of dn, of the logged
+// in user when dn is not given.
+func (r *Router) PasswordPolicy(c *gin.Context) {
+	op := r.ldapOf(c)
+	dn := c.DefaultQuery("dn", op.User)
+	policy, err := op.EffectivePasswordPolicy(dn)
+	if err != nil {
+		abortWithAccountError(c, err)
+		return
+	}
+	if policy == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "no password policy applies to " + dn})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"dn": dn, "policy": policy})
+}
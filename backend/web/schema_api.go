@@ -0,0 +1,79 @@
+package web
+
+import (
+	"net/http"
+
+	"com.ldap/management/ldap"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// loadedSchema makes sure the caller's session has the current schema, it
+// answers 503 with the schema status when none could be loaded.
+func (r *Router) loadedSchema(c *gin.Context) (*ldap.ObjectClassParser, bool) {
+	op := r.ldapOf(c)
+	if err := op.GetObjectClassAttributes(); err != nil {
+		log.Warnln("load schema:", err)
+	}
+	parser := op.Schema()
+	if !parser.Loaded() {
+		c.Header("Retry-After", "5")
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "schema is not available", "status": op.Schemas.Status(op)})
+		return nil, false
+	}
+	return parser, true
+}
+
+// Schema returns the object classes, attribute types, matching rules and syntaxes.
+func (r *Router) Schema(c *gin.Context) {
+	parser, ok := r.loadedSchema(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"schemas":        parser.Objects,
+		"attributeTypes": parser.AttributeTypes,
+		"matchingRules":  parser.MatchingRules,
+		"ldapSyntaxes":   parser.Syntaxes,
+	})
+}
+
+// SchemaAttribute returns one attribute type with the syntax and matching
+// rules inherited from its superiors.
+func (r *Router) SchemaAttribute(c *gin.Context) {
+	name := c.Param("name")
+	parser, ok := r.loadedSchema(c)
+	if !ok {
+		return
+	}
+	attr, exist := parser.EffectiveAttributeType(name)
+	if !exist {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "unknown attribute type " + name})
+		return
+	}
+	syntax := parser.Syntaxes[attr.Syntax]
+	c.JSON(http.StatusOK, gin.H{"attributeType": attr, "syntax": syntax})
+}
+
+// SchemaStatus reports whether the schema of the caller's server is loading,
+// ready or failed, without waiting for a load in progress.
+func (r *Router) SchemaStatus(c *gin.Context) {
+	op := r.ldapOf(c)
+	status := op.Schemas.Status(op)
+	code := http.StatusOK
+	if status.State != ldap.SchemaReady {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, status)
+}
+
+// RefreshSchema reads the schema from the server again, e.g. after a schema change.
+func (r *Router) RefreshSchema(c *gin.Context) {
+	op := r.ldapOf(c)
+	if err := op.RefreshSchema(); err != nil {
+		log.Errorln("refresh schema:", err)
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": err.Error(), "status": op.Schemas.Status(op)})
+		return
+	}
+	c.JSON(http.StatusOK, op.Schemas.Status(op))
+}
@@ -0,0 +1,164 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"com.ldap/management/ldap"
+	log "github.com/sirupsen/logrus"
+)
+
+// Session binds one logged in user to its own LDAP connection.
+type Session struct {
+	ID       string
+	Username string
+	Ldap     *ldap.LDAPOperation
+	LastUsed time.Time
+	// PasswordOnly sessions are issued when the password must be changed
+	// after a reset, they allow nothing but the change and end at Expires.
+	PasswordOnly bool
+	Expires      time.Time
+}
+
+// SessionStore keeps the live sessions keyed by the session id carried in the JWT.
+type SessionStore struct {
+	mu          sync.Mutex
+	sessions    map[string]*Session
+	IdleTimeout time.Duration
+	stop        chan struct{}
+}
+
+var ErrSessionNotFound = errors.New("session not found or expired")
+
+func NewSessionStore(idleTimeout time.Duration) *SessionStore {
+	return &SessionStore{
+		sessions:    make(map[string]*Session),
+		IdleTimeout: idleTimeout,
+		stop:        make(chan struct{}),
+	}
+}
+
+// Create registers the connection under a new random session id.
+func (s *SessionStore) Create(username string, op *ldap.LDAPOperation) (*Session, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+	session := &Session{
+		ID:       id,
+		Username: username,
+		Ldap:     op,
+		LastUsed: time.Now(),
+	}
+	s.mu.Lock()
+	s.sessions[id] = session
+	s.mu.Unlock()
+	return session, nil
+}
+
+// CreatePasswordOnly registers a session that only allows changing the
+// password and ends after lifetime, whether it is used or not.
+func (s *SessionStore) CreatePasswordOnly(username string, op *ldap.LDAPOperation, lifetime time.Duration) (*Session, error) {
+	session, err := s.Create(username, op)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	session.PasswordOnly = true
+	session.Expires = session.LastUsed.Add(lifetime)
+	s.mu.Unlock()
+	return session, nil
+}
+
+// Get returns the session and marks it as used. Idle sessions are closed on access.
+func (s *SessionStore) Get(id string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, exist := s.sessions[id]
+	if !exist {
+		return nil, ErrSessionNotFound
+	}
+	if s.expired(session, time.Now()) {
+		s.remove(session)
+		return nil, ErrSessionNotFound
+	}
+	session.LastUsed = time.Now()
+	return session, nil
+}
+
+// Close drops the session and closes its LDAP connection.
+func (s *SessionStore) Close(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, exist := s.sessions[id]
+	if !exist {
+		return ErrSessionNotFound
+	}
+	return s.remove(session)
+}
+
+// Reap closes every session idle for longer than IdleTimeout or past its end.
+func (s *SessionStore) Reap() {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, session := range s.sessions {
+		if s.expired(session, now) {
+			log.Infof("session of %s expired", session.Username)
+			s.remove(session)
+		}
+	}
+}
+
+// StartReaper periodically reaps idle sessions until Shutdown is called.
+func (s *SessionStore) StartReaper(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.Reap()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Shutdown stops the reaper and closes all sessions.
+func (s *SessionStore) Shutdown() {
+	close(s.stop)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, session := range s.sessions {
+		s.remove(session)
+	}
+}
+
+func (s *SessionStore) expired(session *Session, now time.Time) bool {
+	if !session.Expires.IsZero() && now.After(session.Expires) {
+		return true
+	}
+	return s.IdleTimeout > 0 && now.Sub(session.LastUsed) > s.IdleTimeout
+}
+
+// remove must be called with mu held.
+func (s *SessionStore) remove(session *Session) error {
+	delete(s.sessions, session.ID)
+	if session.Ldap == nil {
+		return nil
+	}
+	return session.Ldap.Close()
+}
+
+func newSessionID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
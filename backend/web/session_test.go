@@ -0,0 +1,158 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"com.ldap/management/ldap"
+	"github.com/gin-gonic/gin"
+)
+
+func TestSessionStore(t *testing.T) {
+	store := NewSessionStore(time.Minute)
+	session, err := store.Create("alice", &ldap.LDAPOperation{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := store.Create("bob", &ldap.LDAPOperation{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.ID == "" || session.ID == other.ID {
+		t.Fatalf("expect distinct session ids, get %q and %q", session.ID, other.ID)
+	}
+
+	got, err := store.Get(session.ID)
+	if err != nil || got != session {
+		t.Fatalf("expect the created session, get %v %v", got, err)
+	}
+	if _, err := store.Get("unknown"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expect ErrSessionNotFound, get %v", err)
+	}
+
+	if err := store.Close(session.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(session.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("a closed session must be gone, get %v", err)
+	}
+	if err := store.Close(session.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expect ErrSessionNotFound closing twice, get %v", err)
+	}
+	if _, err := store.Get(other.ID); err != nil {
+		t.Errorf("closing one session must keep the others, get %v", err)
+	}
+}
+
+func TestSessionIdleExpiry(t *testing.T) {
+	store := NewSessionStore(time.Minute)
+	idle, _ := store.Create("alice", &ldap.LDAPOperation{})
+	active, _ := store.Create("bob", &ldap.LDAPOperation{})
+
+	idle.LastUsed = time.Now().Add(-2 * time.Minute)
+	if _, err := store.Get(idle.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expect an idle session to expire on access, get %v", err)
+	}
+
+	idle, _ = store.Create("alice", &ldap.LDAPOperation{})
+	idle.LastUsed = time.Now().Add(-2 * time.Minute)
+	store.Reap()
+	store.mu.Lock()
+	_, idleLeft := store.sessions[idle.ID]
+	_, activeLeft := store.sessions[active.ID]
+	store.mu.Unlock()
+	if idleLeft || !activeLeft {
+		t.Errorf("Reap must close only idle sessions, idle kept %v, active kept %v", idleLeft, activeLeft)
+	}
+
+	// without a timeout sessions never expire
+	store.IdleTimeout = 0
+	active.LastUsed = time.Now().Add(-24 * time.Hour)
+	if _, err := store.Get(active.ID); err != nil {
+		t.Errorf("expect no expiry without timeout, get %v", err)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := NewRouter()
+	group := r.Engine.Group("/api/v1")
+	group.Use(r.AuthRequire())
+	group.POST("/logout", r.Logout)
+
+	session, _ := r.Sessions.Create("alice", &ldap.LDAPOperation{})
+	token, err := r.issueToken(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logout := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/logout", nil)
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		w := httptest.NewRecorder()
+		r.Engine.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := logout(""); code != http.StatusUnauthorized {
+		t.Errorf("expect 401 without token, get %d", code)
+	}
+	if code := logout(token); code != http.StatusOK {
+		t.Fatalf("expect 200 on logout, get %d", code)
+	}
+	if _, err := r.Sessions.Get(session.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("logout must close the session, get %v", err)
+	}
+	if code := logout(token); code != http.StatusUnauthorized {
+		t.Errorf("expect 401 for the token of a closed session, get %d", code)
+	}
+}
+
+func TestPasswordOnlySession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := NewRouter()
+	group := r.Engine.Group("/api/v1")
+	group.Use(r.AuthRequire())
+	group.GET("/ldap/all", func(c *gin.Context) { c.Status(http.StatusOK) })
+	group.POST("/password", r.ChangePassword)
+	group.POST("/logout", r.Logout)
+
+	session, err := r.Sessions.CreatePasswordOnly("alice", &ldap.LDAPOperation{}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := r.issueToken(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func(method, path string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		r.Engine.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := call(http.MethodGet, "/api/v1/ldap/all"); code != http.StatusForbidden {
+		t.Errorf("expect 403 outside the password change, get %d", code)
+	}
+	// the handler is reached and refuses the empty body
+	if code := call(http.MethodPost, "/api/v1/password"); code != http.StatusBadRequest {
+		t.Errorf("expect the password change to be allowed, get %d", code)
+	}
+	if code := call(http.MethodPost, "/api/v1/logout"); code != http.StatusOK {
+		t.Errorf("expect logout to be allowed, get %d", code)
+	}
+
+	// the session ends after its lifetime even when used
+	session, _ = r.Sessions.CreatePasswordOnly("alice", &ldap.LDAPOperation{}, time.Minute)
+	session.Expires = time.Now().Add(-time.Second)
+	if _, err := r.Sessions.Get(session.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expect the session to end, get %v", err)
+	}
+}
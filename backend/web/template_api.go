@@ -0,0 +1,67 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+
+	"com.ldap/management/ldap"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// ListTemplates returns the entry templates sorted by name.
+func (r *Router) ListTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"templates": ldap.SortedTemplates(r.Templates)})
+}
+
+type renderBody struct {
+	Parent     string              `json:"parent"` // overrides the parent of the template
+	Attributes map[string][]string `json:"attributes"`
+}
+
+// RenderTemplate turns a template and the given attributes into an add
+// request. It is returned as a preview together with the schema problems,
+// with create=true the entry is added.
+func (r *Router) RenderTemplate(c *gin.Context) {
+	tmpl, exist := r.Templates[c.Param("name")]
+	if !exist {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "unknown template " + c.Param("name")})
+		return
+	}
+	var body renderBody
+	if err := c.ShouldBindBodyWithJSON(&body); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	create, _ := strconv.ParseBool(c.Query("create"))
+
+	op := r.ldapOf(c)
+	base, err := op.BaseDN()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	entry, err := tmpl.Render(body.Attributes, body.Parent, base)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
+		return
+	}
+
+	if !create {
+		if err := op.GetObjectClassAttributes(); err != nil {
+			log.Warnln("preview without schema:", err)
+		}
+		problems := ldap.ValidationErrors{}
+		if schema := op.Schema(); schema.Loaded() {
+			problems = schema.ValidateEntry(entry.Attributes)
+		}
+		c.JSON(http.StatusOK, gin.H{"dn": entry.DN, "attributes": entry.Attributes, "errors": problems})
+		return
+	}
+	log.Infof("create %s from template %s", entry.DN, tmpl.Name)
+	if err := op.AddEntry(entry.DN, entry.Attributes); err != nil {
+		abortWithLdapError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"dn": entry.DN, "attributes": entry.Attributes})
+}
@@ -140,18 +140,21 @@ function Ladd() {
         let dn = prefix + "," + values.DN
         dn = dn.split(",").reverse().join(",")
         delete values["DN"]
-        const obj:any = {}
+        // values are sent as arrays, the server never splits them
+        const obj:{[key: string]: string[]} = {}
         const obclass:string[] = []
         Object.entries(values).forEach(val => {
             if(val[0].toLocaleLowerCase().startsWith("objectclass")) {
-                obclass.push(val[1] as string)
+                if (val[1]) {
+                    obclass.push(val[1] as string)
+                }
                 return
-            }else if (val[1] !== undefined) {
-                obj[val[0]] = val[1]
+            }else if (val[1] !== undefined && val[1] !== "") {
+                obj[val[0]] = [val[1] as string]
             }
         })
-        obj["objectClass"] = obclass.join(",")
-        obj["DN"] = dn
+        obj["objectClass"] = obclass
+        obj["DN"] = [dn]
         console.log("obj === ", obj)
 
         try {